@@ -22,6 +22,18 @@ options:
 -out <predict_path>: set the predict path
 -mnt <model_number_type>: double or float	default:double
 -simd <simd_type>: SIMD optimization type (scalar, blas)	default:scalar
+-ffm <field_num>: predict with a field-aware FM model of field_num fields, 0 means plain FM	default:0
+-class_num <num>: expected class num of a multiclass model, 0 means taken from the model meta; multiclass output lines are "label p_0 ... p_{num-1}"	default:0
+-eval <0/1>: compute auc, logloss, mse, ctr ratio and calibration on the labeled input (logloss and accuracy for multiclass), -out becomes optional	default:0
+-auc_bins <bins>: number of histogram bins for approximate auc, 0 means exact auc	default:0
+-calib_buckets <num>: number of equal-width score buckets in the calibration table	default:10
+-eval_json <path>: also write the evaluation result as json to this path
+-group_prefix <prefix>: compute gauc grouped by the first feature whose name starts with prefix, implies -eval 1
+-group_col <0/1>: compute gauc grouped by an extra first column (group label features...), implies -eval 1	default:0
+-ndcg_k <k>: also compute ndcg@k over the groups, 0 means disabled	default:0
+-neg_sample_rate <rate>: negatives of the training data were kept with this rate, scores are corrected to p/(p+(1-p)/rate), 0 means the rate recorded in the model	default:0
+-explain <0/1>: write one json line per sample with the score broken down into bias, per-feature first-order and pairwise contributions, top interactions and features missing from the model, instead of "label score"; not with -eval	default:0
+-explain_top <n>: number of pairwise interactions with the largest absolute value in -explain output	default:10
 `
 }
 
@@ -36,6 +48,18 @@ func main() {
 	out := flag.String("out", "", "predict path")
 	mnt := flag.String("mnt", "double", "model number type")
 	simdType := flag.String("simd", "scalar", "SIMD optimization type")
+	ffmFieldNum := flag.Int("ffm", 0, "ffm field num")
+	classNum := flag.Int("class_num", 0, "multiclass class num")
+	eval := flag.Int("eval", 0, "compute evaluation metrics")
+	aucBins := flag.Int("auc_bins", 0, "auc histogram bins")
+	calibBuckets := flag.Int("calib_buckets", 10, "calibration buckets")
+	evalJSON := flag.String("eval_json", "", "evaluation json path")
+	groupPrefix := flag.String("group_prefix", "", "group key feature prefix")
+	groupCol := flag.Int("group_col", 0, "group key in the first column")
+	ndcgK := flag.Int("ndcg_k", 0, "ndcg@k")
+	negSampleRate := flag.Float64("neg_sample_rate", 0, "negative downsampling rate")
+	explain := flag.Int("explain", 0, "explain scores")
+	explainTop := flag.Int("explain_top", 10, "top interactions to explain")
 
 	flag.Parse()
 
@@ -56,6 +80,23 @@ func main() {
 	}
 	opt.SIMDType = parsedSIMD
 
+	if *ffmFieldNum < 0 {
+		fmt.Fprintln(os.Stderr, "invalid ffm field num")
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	if *ffmFieldNum > 0 {
+		opt.ModelType = model.ModelTypeFFM
+		opt.FieldNum = *ffmFieldNum
+	}
+
+	if *classNum != 0 && (!model.ValidClassNum(*classNum) || *ffmFieldNum > 0) {
+		fmt.Fprintln(os.Stderr, "invalid class num, at least 2 and not with -ffm")
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	opt.ClassNum = *classNum
+
 	// 验证参数
 	if opt.ModelPath == "" {
 		fmt.Fprintln(os.Stderr, "model path required")
@@ -63,7 +104,43 @@ func main() {
 		os.Exit(1)
 	}
 
-	if opt.PredictPath == "" {
+	opt.Eval = *eval != 0
+	opt.GroupPrefix = *groupPrefix
+	opt.GroupColumn = *groupCol != 0
+	if opt.GroupPrefix != "" && opt.GroupColumn {
+		fmt.Fprintln(os.Stderr, "-group_prefix and -group_col are mutually exclusive")
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	if opt.GroupPrefix != "" || opt.GroupColumn {
+		opt.Eval = true
+		opt.EvalOption.Group = true
+		opt.EvalOption.NDCGK = *ndcgK
+	}
+	if *aucBins < 0 || *calibBuckets < 0 || *ndcgK < 0 {
+		fmt.Fprintln(os.Stderr, "invalid auc bins or calibration buckets")
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	opt.EvalOption.AUCBins = *aucBins
+	opt.EvalOption.CalibrationNum = *calibBuckets
+
+	if !model.ValidNegSampleRate(*negSampleRate) {
+		fmt.Fprintln(os.Stderr, "invalid neg_sample_rate")
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+	opt.NegSampleRate = *negSampleRate
+
+	opt.Explain = *explain != 0
+	opt.ExplainTopN = *explainTop
+	if opt.Explain && (opt.Eval || *classNum > 0 || *explainTop < 0) {
+		fmt.Fprintln(os.Stderr, "-explain does not work with -eval or -class_num, and -explain_top must not be negative")
+		fmt.Fprint(os.Stderr, predictHelp())
+		os.Exit(1)
+	}
+
+	if opt.PredictPath == "" && !opt.Eval {
 		fmt.Fprintln(os.Stderr, "predict path required")
 		fmt.Fprint(os.Stderr, predictHelp())
 		os.Exit(1)
@@ -84,5 +161,17 @@ func main() {
 		fmt.Fprintf(os.Stderr, "prediction error: %v\n", err)
 		os.Exit(1)
 	}
+
+	// 输出评估结果
+	if summary := predictor.EvalSummary(); summary != nil {
+		fmt.Print(summary.Text())
+		fmt.Println(summary.JSON())
+		if *evalJSON != "" {
+			if err := os.WriteFile(*evalJSON, []byte(summary.JSON()+"\n"), 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "write eval json error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
 }
 
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/xiongle/alphaFM-go/pkg/serve"
+	"github.com/xiongle/alphaFM-go/pkg/simd"
+)
+
+func serveHelp() string {
+	return `
+usage: ./fm_serve -m <model_path> [<options>]
+
+endpoints:
+POST /predict: score {"features":{"f":1,...}} or {"line":"label f:1 ..."}, or many of them as {"samples":[...]}, returns {"scores":[...]}
+GET /healthz: liveness
+GET /readyz: readiness, 503 while shutting down
+POST /admin/reload: reload the model file, the old model is kept if loading fails
+GET /admin/model: model version, reload count and last reload error
+with -grpc_addr, the same scoring is served over gRPC (service alphafm.serve.Predictor in pkg/serve/pb/predictor.proto)
+
+options:
+-m <model_path>: set the model path
+-mf <model_format>: set the model format, txt or bin	default:txt
+-dim <factor_num>: dim of 2-way interactions	default:8
+-simd <simd_type>: SIMD optimization type (scalar, blas)	default:scalar
+-addr <address>: listen address	default::8080
+-grpc_addr <address>: gRPC listen address, empty means no gRPC server	default:
+-max_concurrent <num>: max number of requests scored at the same time	default:number of CPUs
+-queue_timeout <ms>: how long a request waits for a free slot before 429, 0 means reject at once	default:100
+-max_batch <num>: max number of samples in one request	default:1000
+-max_body <bytes>: max request body size	default:8388608
+-shutdown_timeout <seconds>: on SIGINT/SIGTERM, how long to wait for in-flight requests	default:10
+-watch_interval <seconds>: check the model file at this interval and reload it when it changes, 0 means off	default:0
+SIGHUP also reloads the model.
+`
+}
+
+func main() {
+	opt := serve.NewOption()
+
+	modelPath := flag.String("m", "", "model path")
+	modelFormat := flag.String("mf", "txt", "model format")
+	dim := flag.Int("dim", 8, "factor num")
+	simdType := flag.String("simd", "scalar", "SIMD optimization type")
+	addr := flag.String("addr", ":8080", "listen address")
+	grpcAddr := flag.String("grpc_addr", "", "gRPC listen address")
+	maxConcurrent := flag.Int("max_concurrent", opt.MaxConcurrent, "max concurrent requests")
+	queueTimeout := flag.Int("queue_timeout", 100, "queue timeout in ms")
+	maxBatch := flag.Int("max_batch", opt.MaxBatch, "max samples per request")
+	maxBody := flag.Int64("max_body", opt.MaxBodyBytes, "max request body bytes")
+	shutdownTimeout := flag.Int("shutdown_timeout", 10, "shutdown timeout in seconds")
+	watchInterval := flag.Int("watch_interval", 0, "model watch interval in seconds")
+
+	flag.Parse()
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "model path required")
+		fmt.Fprint(os.Stderr, serveHelp())
+		os.Exit(1)
+	}
+	parsedSIMD, err := simd.ParseVectorOpsType(*simdType)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid simd type: %v\n", err)
+		fmt.Fprint(os.Stderr, serveHelp())
+		os.Exit(1)
+	}
+	if *maxConcurrent <= 0 || *queueTimeout < 0 || *maxBatch <= 0 || *maxBody <= 0 || *shutdownTimeout < 0 || *watchInterval < 0 {
+		fmt.Fprintln(os.Stderr, "invalid concurrency or request limits")
+		fmt.Fprint(os.Stderr, serveHelp())
+		os.Exit(1)
+	}
+	opt.SIMDType = parsedSIMD
+	opt.MaxConcurrent = *maxConcurrent
+	opt.QueueTimeout = time.Duration(*queueTimeout) * time.Millisecond
+	opt.MaxBatch = *maxBatch
+	opt.MaxBodyBytes = *maxBody
+
+	// 加载模型
+	fmt.Println("load model...")
+	holder, err := serve.NewModelHolder(*modelPath, *modelFormat, *dim)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load model error: %v\n", err)
+		os.Exit(1)
+	}
+	lm := holder.Current()
+	fmt.Printf("model loading finished, version: %s, features: %d\n", lm.Version, lm.Model.FeatureNum())
+
+	server, err := serve.NewServer(holder, opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create server error: %v\n", err)
+		os.Exit(1)
+	}
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// gRPC服务与HTTP服务共用模型和并发限制
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "grpc listen error: %v\n", err)
+			os.Exit(1)
+		}
+		grpcServer = server.NewGRPCServer()
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				fmt.Fprintf(os.Stderr, "grpc serve error: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+		fmt.Printf("serving gRPC on %s\n", *grpcAddr)
+	}
+
+	// 热更新：SIGHUP或定时检查模型文件，加载结果由holder输出
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			holder.Reload()
+		}
+	}()
+	if *watchInterval > 0 {
+		go holder.Watch(time.Duration(*watchInterval)*time.Second, nil)
+	}
+
+	// SIGINT/SIGTERM: 先置为未就绪，再等待处理中的请求完成后退出
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		sig := <-sigCh
+		fmt.Printf("received signal %v, shutting down\n", sig)
+		server.SetReady(false)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*shutdownTimeout)*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			go func() {
+				// 超时后强制关闭仍未结束的流
+				<-ctx.Done()
+				grpcServer.Stop()
+			}()
+		}
+		if err := httpServer.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "shutdown error: %v\n", err)
+		}
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+		close(done)
+	}()
+
+	fmt.Printf("serving on %s, max concurrent requests: %d\n", *addr, opt.MaxConcurrent)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "serve error: %v\n", err)
+		os.Exit(1)
+	}
+	<-done
+}
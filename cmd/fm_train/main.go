@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/xiongle/alphaFM-go/pkg/frame"
 	"github.com/xiongle/alphaFM-go/pkg/model"
+	"github.com/xiongle/alphaFM-go/pkg/sample"
 	"github.com/xiongle/alphaFM-go/pkg/simd"
 )
 
@@ -37,6 +41,46 @@ options:
 -fvs <force_v_sparse>: if fvs is 1, set vi = 0 whenever wi = 0	default:0
 -mnt <model_number_type>: double or float	default:double
 -simd <simd_type>: SIMD optimization type (scalar, blas)	default:scalar
+-opt <optimizer>: ftrl, adagrad, adam or sgd, w_alpha/v_alpha are the learning rates of all optimizers	default:ftrl
+-loss <loss>: training objective, logistic for 0/1 labels, squared for real-valued targets, poisson for non-negative counts	default:logistic
+-adam_beta1 <beta1>: decay rate of the first moment for adam	default:0.9
+-adam_beta2 <beta2>: decay rate of the second moment for adam	default:0.999
+-adam_eps <eps>: epsilon for adam	default:1e-8
+-epoch <epoch_num>: number of passes over the input, samples are cached after the first pass	default:1
+-epoch_mem <mem_mb>: memory limit of the sample cache in MB, spills to disk when exceeded	default:1024
+-epoch_cache <cache_path>: spill file path of the sample cache	default:a temp file
+-shuffle <shuffle>: if shuffle is 1, shuffle the cached samples before each replayed epoch	default:0
+-shuffle_seed <seed>: random seed for shuffling	default:1
+-pv_window <window>: number of recent samples used for the progressive validation auc	default:100000
+-val <val_path>: validation file, scored with the current model at the end of each epoch and every val_lines lines
+-val_lines <lines>: also validate every val_lines training lines, 0 means only at the end of each epoch	default:0
+-val_metric <metric>: metric for keeping the best model and early stopping, logloss, auc or mse (only mse for squared and poisson)	default:logloss, mse for squared and poisson
+-patience <patience>: stop training after patience validations without improvement, 0 means never stop	default:0
+-ckpt <ckpt_path>: write checkpoints of the model to ckpt_path atomically while training continues, only in the first epoch with -epoch > 1
+-ckpt_lines <lines>: write a checkpoint every ckpt_lines input lines	default:0
+-ckpt_interval <seconds>: write a checkpoint every ckpt_interval seconds	default:0
+-skip <lines>: skip the first lines of the input, used with -im <ckpt_path> to resume from a checkpoint	default:0
+-sig_model <path>: on SIGINT/SIGTERM the current model is written to this path instead of -m, with -val the best validated model also goes to <path>.best	default:-m
+-hash <0/1>: feature hashing, features are stored by 64-bit hashed keys instead of names	default:0
+-hash_seed <seed>: seed of feature hashing	default:0
+-hash_buckets <buckets>: number of hash buckets, 0 means the full 64-bit key space; >0 preallocates the feature store for all buckets	default:0
+-hash_stats <0/1>: report feature hashing collisions at the end of training (keeps a fingerprint per distinct feature)	default:0
+-hogwild <0/1>: lock-free training, concurrent updates of the same feature may overwrite each other and results are not reproducible	default:0
+-seed <seed>: initialize each feature from a hash of its name and the seed, 0 means time-based random initialization	default:0
+-deterministic <0/1>: batch-synchronous training, the model does not depend on -core (fm only)	default:0
+-sync_batch <size>: mini-batch size of deterministic training	default:256
+-min_count <count>: create parameters for a feature only after it has appeared this many times (count-min sketch)	default:0
+-admit_width <width>: counters per row of the count-min sketch used by -min_count	default:1048576
+-admit_prob <p>: admit a new feature with probability p on each appearance	default:0
+-evict_lines <lines>: sweep the model for features to evict every evict_lines input lines	default:0
+-evict_stale <samples>: evict features not seen in the last evict_stale samples	default:0
+-evict_w <threshold>: with -evict_v, evict features whose |w| and ||v|| stay below the thresholds for two sweeps	default:0
+-evict_v <threshold>: see -evict_w	default:0
+-neg_sample_rate <rate>: record that negatives of the training data were kept with this rate, fm_predict corrects the scores accordingly, 0 means no downsampling	default:0
+-rank <objective>: pairwise ranking, bpr or ranknet, samples are "group label feature1:value1 ..." with the lines of a group adjacent	default:none
+-rank_neg <num>: negatives sampled within the group for each positive with -rank bpr	default:1
+-ffm <field_num>: train a field-aware FM with field_num fields, samples are "label field:feature:value ...", 0 means plain FM	default:0
+-class_num <num>: train a softmax multiclass FM with num classes, labels are class indices 0..num-1, 0 means binary	default:0
 `
 }
 
@@ -88,6 +132,46 @@ func main() {
 	fvs := flag.Int("fvs", 0, "force v sparse")
 	mnt := flag.String("mnt", "double", "model number type")
 	simdType := flag.String("simd", "scalar", "SIMD optimization type")
+	ffmFieldNum := flag.Int("ffm", 0, "ffm field num")
+	optimizer := flag.String("opt", "ftrl", "optimizer")
+	lossName := flag.String("loss", model.LossLogistic, "training objective")
+	adamBeta1 := flag.Float64("adam_beta1", 0.9, "adam beta1")
+	adamBeta2 := flag.Float64("adam_beta2", 0.999, "adam beta2")
+	adamEps := flag.Float64("adam_eps", 1e-8, "adam eps")
+	epochNum := flag.Int("epoch", 1, "epoch num")
+	epochMem := flag.Int64("epoch_mem", 1024, "sample cache memory limit in MB")
+	epochCache := flag.String("epoch_cache", "", "sample cache spill path")
+	shuffle := flag.Int("shuffle", 0, "shuffle between epochs")
+	shuffleSeed := flag.Int64("shuffle_seed", 1, "shuffle seed")
+	pvWindow := flag.Int("pv_window", 100000, "progressive validation window")
+	valPath := flag.String("val", "", "validation path")
+	valLines := flag.Int("val_lines", 0, "validate every val_lines lines")
+	valMetric := flag.String("val_metric", "", "validation metric")
+	patience := flag.Int("patience", 0, "early stopping patience")
+	ckptPath := flag.String("ckpt", "", "checkpoint path")
+	ckptLines := flag.Int("ckpt_lines", 0, "checkpoint every ckpt_lines lines")
+	ckptInterval := flag.Int("ckpt_interval", 0, "checkpoint every ckpt_interval seconds")
+	skipLines := flag.Int("skip", 0, "skip lines")
+	sigModelPath := flag.String("sig_model", "", "model path on signal")
+	hashed := flag.Int("hash", 0, "feature hashing")
+	hashSeed := flag.Uint64("hash_seed", 0, "feature hashing seed")
+	hashBuckets := flag.Uint64("hash_buckets", 0, "feature hashing buckets")
+	hashStats := flag.Int("hash_stats", 0, "feature hashing collision stats")
+	hogwild := flag.Int("hogwild", 0, "lock-free training")
+	seed := flag.Int64("seed", 0, "random seed")
+	deterministic := flag.Int("deterministic", 0, "deterministic training")
+	syncBatch := flag.Int("sync_batch", 256, "mini-batch size of deterministic training")
+	minCount := flag.Int("min_count", 0, "feature admission min count")
+	admitWidth := flag.Int("admit_width", 1<<20, "count-min sketch width")
+	admitProb := flag.Float64("admit_prob", 0, "feature admission probability")
+	evictLines := flag.Int("evict_lines", 0, "eviction sweep interval")
+	evictStale := flag.Int64("evict_stale", 0, "evict features not seen for evict_stale samples")
+	evictW := flag.Float64("evict_w", 0, "eviction threshold of |w|")
+	evictV := flag.Float64("evict_v", 0, "eviction threshold of ||v||")
+	negSampleRate := flag.Float64("neg_sample_rate", 0, "negative downsampling rate")
+	rank := flag.String("rank", "", "pairwise ranking objective")
+	rankNeg := flag.Int("rank_neg", 1, "negatives per positive for bpr")
+	classNum := flag.Int("class_num", 0, "multiclass class num")
 
 	flag.Parse()
 
@@ -129,6 +213,169 @@ func main() {
 	}
 	opt.SIMDType = parsedSIMD
 
+	if !model.IsValidOptimizer(*optimizer) {
+		fmt.Fprintf(os.Stderr, "invalid optimizer: %s\n", *optimizer)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.Optimizer = *optimizer
+	opt.AdamBeta1 = *adamBeta1
+	opt.AdamBeta2 = *adamBeta2
+	opt.AdamEps = *adamEps
+
+	if !model.IsValidLoss(*lossName) {
+		fmt.Fprintf(os.Stderr, "invalid loss: %s\n", *lossName)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.Loss = *lossName
+
+	if *epochNum < 1 {
+		fmt.Fprintln(os.Stderr, "invalid epoch num")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.EpochNum = *epochNum
+	opt.EpochMemLimit = *epochMem << 20
+	opt.EpochCachePath = *epochCache
+	opt.Shuffle = *shuffle == 1
+	opt.ShuffleSeed = *shuffleSeed
+
+	if *pvWindow < 0 {
+		fmt.Fprintln(os.Stderr, "invalid progressive validation window")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.PVWindow = *pvWindow
+
+	if *valMetric == "" {
+		*valMetric = model.ValMetricLogLoss
+		if opt.Loss != model.LossLogistic {
+			*valMetric = model.ValMetricMSE
+		}
+	}
+	if !model.IsValidValMetric(*valMetric, opt.Loss) {
+		fmt.Fprintf(os.Stderr, "invalid validation metric for %s loss: %s\n", opt.Loss, *valMetric)
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if *valLines < 0 || *patience < 0 {
+		fmt.Fprintln(os.Stderr, "invalid val_lines or patience")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.ValMetric = *valMetric
+	opt.ValPatience = *patience
+
+	if *ckptLines < 0 || *ckptInterval < 0 || *skipLines < 0 {
+		fmt.Fprintln(os.Stderr, "invalid ckpt_lines, ckpt_interval or skip")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if *ckptPath != "" && *ckptLines == 0 && *ckptInterval == 0 {
+		fmt.Fprintln(os.Stderr, "ckpt_lines or ckpt_interval required with -ckpt")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+
+	opt.Hashed = *hashed != 0
+	opt.HashSeed = *hashSeed
+	opt.HashBuckets = *hashBuckets
+	opt.HashStats = *hashStats != 0
+	opt.Hogwild = *hogwild != 0
+
+	if *syncBatch <= 0 {
+		fmt.Fprintln(os.Stderr, "invalid sync_batch")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if *deterministic != 0 && (opt.Hogwild || *ffmFieldNum > 0) {
+		fmt.Fprintln(os.Stderr, "deterministic training does not support -hogwild or -ffm")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.Seed = *seed
+	opt.Deterministic = *deterministic != 0
+	opt.SyncBatch = *syncBatch
+	if opt.Seed != 0 {
+		rand.Seed(opt.Seed)
+	}
+
+	if *minCount < 0 || *admitWidth <= 0 || *admitProb < 0 || *admitProb > 1 {
+		fmt.Fprintln(os.Stderr, "invalid min_count, admit_width or admit_prob")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if *minCount > 1 && *admitProb > 0 && *admitProb < 1 {
+		fmt.Fprintln(os.Stderr, "min_count and admit_prob can not be used together")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.AdmitMinCount = *minCount
+	opt.AdmitWidth = *admitWidth
+	opt.AdmitProb = *admitProb
+
+	if *evictLines < 0 || *evictStale < 0 || *evictW < 0 || *evictV < 0 {
+		fmt.Fprintln(os.Stderr, "invalid evict_lines, evict_stale, evict_w or evict_v")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.EvictStale = *evictStale
+	opt.EvictMinW = *evictW
+	opt.EvictMinV = *evictV
+	if *evictLines > 0 && *evictStale == 0 && *evictW == 0 && *evictV == 0 {
+		fmt.Fprintln(os.Stderr, "evict_stale, evict_w or evict_v required with -evict_lines")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+
+	if !model.ValidNegSampleRate(*negSampleRate) || (*negSampleRate > 0 && opt.Loss != model.LossLogistic) {
+		fmt.Fprintln(os.Stderr, "invalid neg_sample_rate, only applies to the logistic loss")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	opt.NegSampleRate = *negSampleRate
+
+	if *rank != "" {
+		if !model.IsValidRank(*rank) || *rankNeg <= 0 {
+			fmt.Fprintln(os.Stderr, "invalid rank or rank_neg")
+			fmt.Fprint(os.Stderr, trainHelp())
+			os.Exit(1)
+		}
+		if opt.Loss != model.LossLogistic || *ffmFieldNum > 0 || opt.Deterministic || opt.Shuffle || *valPath != "" {
+			fmt.Fprintln(os.Stderr, "pairwise ranking does not support -loss, -ffm, -deterministic, -shuffle or -val")
+			fmt.Fprint(os.Stderr, trainHelp())
+			os.Exit(1)
+		}
+		opt.Rank = *rank
+		opt.RankNeg = *rankNeg
+	}
+
+	if *classNum != 0 {
+		if !model.ValidClassNum(*classNum) {
+			fmt.Fprintln(os.Stderr, "invalid class num, at least 2")
+			fmt.Fprint(os.Stderr, trainHelp())
+			os.Exit(1)
+		}
+		if opt.Loss != model.LossLogistic || *ffmFieldNum > 0 || opt.Rank != "" || opt.Deterministic ||
+			opt.ForceVSparse || opt.NegSampleRate > 0 || *valPath != "" {
+			fmt.Fprintln(os.Stderr, "multiclass does not support -loss, -ffm, -rank, -deterministic, -fvs, -neg_sample_rate or -val")
+			fmt.Fprint(os.Stderr, trainHelp())
+			os.Exit(1)
+		}
+		opt.ClassNum = *classNum
+	}
+
+	if *ffmFieldNum < 0 {
+		fmt.Fprintln(os.Stderr, "invalid ffm field num")
+		fmt.Fprint(os.Stderr, trainHelp())
+		os.Exit(1)
+	}
+	if *ffmFieldNum > 0 {
+		opt.ModelType = model.ModelTypeFFM
+		opt.FieldNum = *ffmFieldNum
+	}
+
 	if *initModelPath != "" {
 		opt.BInit = true
 	}
@@ -151,22 +398,256 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Println("model loading finished")
+		if lines := trainer.ResumeLines(); lines > 0 && int64(*skipLines) != lines {
+			fmt.Printf("Warning: initial model is a checkpoint at %d input lines, but -skip is %d\n", lines, *skipLines)
+		}
+	}
+
+	if bytes, ok := trainer.BucketStoreBytes(); ok {
+		fmt.Printf("feature store: %d hash buckets preallocated, %.2f MB\n", opt.HashBuckets, float64(bytes)/(1<<20))
+	}
+
+	// 加载验证集
+	if *valPath != "" {
+		if err := trainer.LoadValidationSet(*valPath); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load validation set: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// 多轮训练时缓存首轮解析的样本
+	var cache *sample.SampleCache
+	if opt.EpochNum > 1 {
+		cache = sample.NewSampleCache(opt.EpochMemLimit, opt.EpochCachePath)
+		defer cache.Close()
+		trainer.SetSampleCache(cache)
 	}
 
 	// 运行训练框架
 	pcFrame := frame.NewPCFrame()
-	pcFrame.Init(trainer, opt.ThreadsNum)
+	if opt.Deterministic {
+		// 批次按输入顺序逐个训练，批内由训练器并行
+		pcFrame.Init(trainer, 1)
+	} else {
+		pcFrame.Init(trainer, opt.ThreadsNum)
+	}
+	if opt.Rank != "" {
+		// 同一分组的样本在同一批次中组成样本对
+		pcFrame.SetGroupKey(func(line string) string {
+			group, _, _ := sample.SplitGroupColumn(line)
+			return group
+		})
+	}
+	epoch := 1
+	stopped := false
+	if *valPath != "" && *valLines > 0 {
+		pcFrame.AddBarrier(*valLines, func(lineNum int) bool {
+			stopped = !validate(trainer, fmt.Sprintf("epoch %d, %d lines", epoch, lineNum))
+			return !stopped
+		})
+	}
+
+	// 周期性淘汰长时间未出现或权重过小的特征
+	if *evictLines > 0 {
+		pcFrame.AddBarrier(*evictLines, func(lineNum int) bool {
+			stats := trainer.Evict()
+			fmt.Printf("epoch %d, %d lines, eviction: %s\n", epoch, lineNum, stats)
+			return true
+		})
+	}
+
+	// 周期性写入检查点，记录已训练的输入行数
+	// 回放阶段的进度无法用输入行数表示，用-skip续训会跳过全部输入并丢掉剩余的轮次，因此只在首轮写入
+	var checkpointer *model.Checkpointer
+	if *ckptPath != "" {
+		checkpointer = model.NewCheckpointer(*ckptPath, opt.ModelFormat)
+		replayNoted := false
+		save := func(lineNum int) bool {
+			if epoch > 1 {
+				if !replayNoted {
+					fmt.Println("checkpoints are not written in replay epochs, the last checkpoint is from epoch 1")
+					replayNoted = true
+				}
+				return true
+			}
+			checkpointer.Save(trainer.Snapshot(), int64(lineNum))
+			return true
+		}
+		if *ckptLines > 0 {
+			pcFrame.AddBarrier(*ckptLines, save)
+		}
+		if *ckptInterval > 0 {
+			pcFrame.AddIntervalBarrier(time.Duration(*ckptInterval)*time.Second, save)
+		}
+	}
+
+	// SIGINT/SIGTERM: 停止读取输入，训练完已读入的批次后输出模型；再次收到信号时立即退出
+	var received atomic.Value
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		received.Store(sig)
+		fmt.Printf("received signal %v, stop reading input and finish in-flight batches\n", sig)
+		pcFrame.Stop()
+		sig = <-sigCh
+		fmt.Printf("received signal %v again, exit without saving the model\n", sig)
+		os.Exit(signalExitCode(sig))
+	}()
+
+	pcFrame.SetSkipLines(*skipLines)
 	if err := pcFrame.Run(os.Stdin); err != nil {
 		fmt.Fprintf(os.Stderr, "training error: %v\n", err)
 		os.Exit(1)
 	}
+	lossLabel := "logloss"
+	switch {
+	case opt.Rank != "":
+		lossLabel = opt.Rank + " pairwise loss"
+	case opt.ClassNum > 0:
+		lossLabel = "multiclass logloss"
+	case opt.Loss != model.LossLogistic:
+		lossLabel = opt.Loss + " loss"
+	}
+	loss, num := trainer.TakeTrainLoss()
+	fmt.Printf("epoch 1/%d finished, samples: %d, train %s: %.6f\n", opt.EpochNum, num, lossLabel, loss)
+	if *valPath != "" && !stopped && !pcFrame.Stopped() {
+		stopped = !validate(trainer, "epoch 1 finished")
+	}
+
+	// 回放缓存的样本
+	if cache != nil && !stopped && !pcFrame.Stopped() {
+		trainer.SetSampleCache(nil)
+		if err := cache.Finish(); err != nil {
+			fmt.Fprintf(os.Stderr, "sample cache error: %v\n", err)
+			os.Exit(1)
+		}
+		rng := rand.New(rand.NewSource(opt.ShuffleSeed))
+		for epoch = 2; epoch <= opt.EpochNum && !stopped; epoch++ {
+			var order []int
+			if opt.Shuffle {
+				order = cache.ShuffledOrder(rng)
+			}
+			if err := pcFrame.Replay(cache, order); err != nil {
+				fmt.Fprintf(os.Stderr, "training error: %v\n", err)
+				os.Exit(1)
+			}
+			loss, num := trainer.TakeTrainLoss()
+			fmt.Printf("epoch %d/%d finished, samples: %d, train %s: %.6f\n", epoch, opt.EpochNum, num, lossLabel, loss)
+			if pcFrame.Stopped() {
+				break
+			}
+			if *valPath != "" && !stopped {
+				stopped = !validate(trainer, fmt.Sprintf("epoch %d finished", epoch))
+			}
+		}
+	}
+	if stopped {
+		fmt.Println("early stopping, output the best model")
+	}
+
+	if checkpointer != nil {
+		if err := checkpointer.Wait(); err != nil {
+			fmt.Printf("Warning: last checkpoint failed: %v\n", err)
+		}
+	}
+
+	if rejected, ok := trainer.AdmissionRejected(); ok {
+		fmt.Printf("feature admission: features: %d, ignored occurrences of unadmitted features: %d\n",
+			trainer.FeatureNum(), rejected)
+	}
+
+	if stats, ok := trainer.HashStats(); ok {
+		fmt.Printf("feature hashing: features: %d, keys: %d, collisions: %d (%.4f%%)\n",
+			stats.Features, stats.Keys, stats.Collisions, 100*stats.CollisionRate())
+	}
+
+	// 首轮的渐进式验证结果
+	pv := trainer.ProgressiveReport()
+	if opt.Loss == model.LossLogistic && opt.Rank == "" && opt.ClassNum == 0 {
+		fmt.Printf("progressive validation: samples: %d, logloss: %.6f, auc: %.6f, window auc(%d): %.6f\n",
+			pv.Count, pv.LogLoss, pv.AUC, pv.WindowSize, pv.WindowAUC)
+	} else {
+		fmt.Printf("progressive validation: samples: %d, %s: %.6f\n", pv.Count, lossLabel, pv.LogLoss)
+	}
 
 	// 输出模型
+	outputPath := opt.ModelPath
+	sig, interrupted := received.Load().(os.Signal)
+	if interrupted {
+		if epoch > 1 {
+			fmt.Printf("interrupted by %v in epoch %d after %d input lines and %d replayed samples\n",
+				sig, epoch, pcFrame.LinesRead(), pcFrame.Replayed())
+		} else {
+			fmt.Printf("interrupted by %v after %d input lines consumed\n", sig, pcFrame.LinesRead())
+		}
+		if *sigModelPath != "" {
+			outputPath = *sigModelPath
+		}
+	}
 	fmt.Println("output model...")
-	if err := trainer.OutputModel(opt.ModelPath, opt.ModelFormat); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to output model: %v\n", err)
-		os.Exit(1)
+	if interrupted || !trainer.HasBestModel() {
+		// 中断时输出当前的模型，保留最后一次验证之后的训练；验证指标最好的快照另存
+		if err := trainer.OutputModel(outputPath, opt.ModelFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to output model: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("current model written to %s\n", outputPath)
+		if trainer.HasBestModel() {
+			bestPath := outputPath + ".best"
+			score, err := trainer.OutputBestModel(bestPath, opt.ModelFormat)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to output best model: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("best validated model (%.6f) written to %s\n", score, bestPath)
+		}
+	} else {
+		score, err := trainer.OutputBestModel(outputPath, opt.ModelFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to output model: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("best validated model (%.6f) written to %s\n", score, outputPath)
 	}
 	fmt.Println("model outputting finished")
+
+	if interrupted {
+		if cache != nil {
+			cache.Close()
+		}
+		os.Exit(signalExitCode(sig))
+	}
 }
 
+// signalExitCode 被信号中断时的退出码，与shell约定一致为128+信号值
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// lastValidation 上一次输出的验证结果
+var lastValidation *model.ValidationResult
+
+// validate 在验证集上评估当前模型，返回false表示应当早停
+// 上次验证以来没有训练新样本时（如暂停点恰好在一轮结束处）不重复输出
+func validate(trainer *model.FTRLTrainer, at string) bool {
+	r := trainer.Validate()
+	if r == lastValidation {
+		return !r.Stop
+	}
+	lastValidation = r
+	mark := ""
+	if r.Improved {
+		mark = " *"
+	}
+	if r.Summary.Regression {
+		fmt.Printf("validation (%s): mse: %.6f, best: %.6f%s\n", at, r.Summary.MSE, r.Best, mark)
+	} else {
+		fmt.Printf("validation (%s): logloss: %.6f, auc: %.6f, best: %.6f%s\n",
+			at, r.Summary.LogLoss, r.Summary.AUC, r.Best, mark)
+	}
+	return !r.Stop
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"os"
@@ -26,59 +25,29 @@ options:
 }
 
 func printInfo(inputPath string) error {
-	info, err := model.ReadInfo(inputPath)
-	if err != nil {
+	mbf := model.NewModelBinFile()
+	if err := mbf.OpenForRead(inputPath); err != nil {
 		return err
 	}
+	defer mbf.Close()
 
-	fmt.Printf("format_version: 1\n")
-	fmt.Printf("number_byte_length: %d", info.NumByteLen)
-	if info.NumByteLen == 8 {
-		fmt.Print("(double)")
-	} else if info.NumByteLen == 4 {
-		fmt.Print("(float)")
-	}
-	fmt.Println()
-	fmt.Printf("factor_num: %d\n", info.FactorNum)
-	fmt.Printf("feature_num: %d\n", info.FeaNum)
-	fmt.Printf("nonzero_feature_num: %d\n", info.NonzeroFeaNum)
-	fmt.Printf("success_flag: %v\n", info.SuccessFlag == 1)
-
+	mbf.PrintInfo()
 	return nil
 }
 
 func binToTxt(inputPath, outputPath string, onlyNonZero bool) error {
-	// 打开输入文件
-	mbf := model.NewModelBinFile()
-	if err := mbf.OpenForRead(inputPath); err != nil {
-		return fmt.Errorf("open input file error: %v", err)
-	}
-	defer mbf.Close()
-
-	info := mbf.GetInfo()
-
-	// 打开输出
-	var writer *bufio.Writer
-	var outFile *os.File
+	// 打开输出，未指定-om时写到标准输出
+	out := os.Stdout
 	if outputPath != "" {
 		f, err := os.Create(outputPath)
 		if err != nil {
 			return fmt.Errorf("open output file error: %v", err)
 		}
-		outFile = f
-		defer outFile.Close()
-		writer = bufio.NewWriter(outFile)
-	} else {
-		writer = bufio.NewWriter(os.Stdout)
+		defer f.Close()
+		out = f
 	}
-	defer writer.Flush()
-
-	// 简化版本：提示用户功能尚未完全实现
-	fmt.Fprintf(writer, "# Binary to text conversion\n")
-	fmt.Fprintf(writer, "# Model info: factor_num=%d, feature_num=%d\n", info.FactorNum, info.FeaNum)
-	fmt.Fprintf(writer, "# Note: Full binary model support is under development\n")
 
-	return nil
+	return model.ConvertBinToTxt(inputPath, out, onlyNonZero)
 }
 
 func txtToBin(inputPath, outputPath string, factorNum int, useFloat32 bool) error {
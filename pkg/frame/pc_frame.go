@@ -2,9 +2,11 @@ package frame
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 )
 
 // Task 任务接口
@@ -12,6 +14,28 @@ type Task interface {
 	RunTask(dataBuffer []string) error
 }
 
+// ProgressReporter 可选接口，任务实现后在输出处理行数时附带进度信息
+type ProgressReporter interface {
+	Progress() string
+}
+
+// SkipHandler 可选接口，任务实现后Run跳过的行按批次交给SkipTask处理（不作为任务运行），
+// 多轮训练从检查点续训时用于把已训练过的行加入回放缓存
+type SkipHandler interface {
+	SkipTask(dataBuffer []string) error
+}
+
+// Barrier 处理过程中的暂停点
+// 生产者每处理Every行（在批次边界上判断）等待已发送的批次全部处理完成，然后在生产者线程中调用Fn，
+// 或每隔Interval时间触发；此时没有消费者在运行任务，Fn可以安全地读取模型；Fn返回false时停止读取输入
+type Barrier struct {
+	Every    int
+	Interval time.Duration
+	Fn       func(lineNum int) bool
+	next     int
+	nextTime time.Time
+}
+
 // PCFrame 生产者-消费者框架
 type PCFrame struct {
 	task       Task
@@ -21,6 +45,13 @@ type PCFrame struct {
 	buffer     chan []string
 	done       chan struct{}
 	wg         sync.WaitGroup
+	pending    sync.WaitGroup // 已发送但未处理完成的批次
+	barriers   []*Barrier
+	skipLines  int // Run时跳过输入的前skipLines行（断点续训）
+	linesRead  int // Run读取的输入行数（含跳过的行）
+	replayed   int // 最近一次Replay回放的样本数
+	groupKey   func(line string) string // 非nil时同一分组的连续行不拆到不同批次
+	stopOnce   sync.Once
 }
 
 // NewPCFrame 创建PC框架
@@ -39,8 +70,95 @@ func (f *PCFrame) Init(task Task, threadNum int) {
 	f.done = make(chan struct{})
 }
 
+// AddBarrier 添加按行数触发的暂停点，Run和Replay都会触发，行数在每次Run/Replay开始时重新计数
+func (f *PCFrame) AddBarrier(every int, fn func(lineNum int) bool) {
+	f.barriers = append(f.barriers, &Barrier{Every: every, Fn: fn})
+}
+
+// AddIntervalBarrier 添加按时间间隔触发的暂停点
+func (f *PCFrame) AddIntervalBarrier(interval time.Duration, fn func(lineNum int) bool) {
+	f.barriers = append(f.barriers, &Barrier{Interval: interval, Fn: fn})
+}
+
+// SetSkipLines 设置Run时跳过的输入行数，跳过的行计入行号
+func (f *PCFrame) SetSkipLines(n int) {
+	f.skipLines = n
+}
+
+// SetGroupKey 开启分组批次：groupKey相同的连续行（Replay时为Group相同的连续样本）总在同一批次中
+// 批次达到bufSize行后延续到当前分组结束，批次边界和暂停点的行号不再是bufSize的整数倍
+func (f *PCFrame) SetGroupKey(groupKey func(line string) string) {
+	f.groupKey = groupKey
+}
+
+// Stop 停止读取输入（可在任意goroutine中调用，如信号处理）
+// 已读入的行和已发送的批次仍会处理完，之后Run/Replay返回
+func (f *PCFrame) Stop() {
+	f.stopOnce.Do(func() { close(f.done) })
+}
+
+// Stopped 是否已调用Stop
+func (f *PCFrame) Stopped() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Replayed 最近一次Replay回放的样本数
+func (f *PCFrame) Replayed() int {
+	return f.replayed
+}
+
+// LinesRead Run结束后返回读取的输入行数（含跳过的行）
+func (f *PCFrame) LinesRead() int {
+	return f.linesRead
+}
+
+// resetBarriers 重置暂停点的计数，startLine为起始行号
+func (f *PCFrame) resetBarriers(startLine int) {
+	now := time.Now()
+	for _, b := range f.barriers {
+		if b.Every > 0 {
+			b.next = (startLine/b.Every + 1) * b.Every
+		}
+		b.nextTime = now.Add(b.Interval)
+	}
+}
+
+// checkBarriers 在批次边界检查暂停点，返回false表示停止读取输入
+func (f *PCFrame) checkBarriers(lineNum int) bool {
+	waited := false
+	cont := true
+	now := time.Now()
+	for _, b := range f.barriers {
+		switch {
+		case b.Every > 0 && lineNum >= b.next:
+			for b.next <= lineNum {
+				b.next += b.Every
+			}
+		case b.Interval > 0 && !now.Before(b.nextTime):
+			b.nextTime = now.Add(b.Interval)
+		default:
+			continue
+		}
+		if !waited {
+			f.pending.Wait()
+			waited = true
+		}
+		if !b.Fn(lineNum) {
+			cont = false
+		}
+	}
+	return cont
+}
+
 // Run 运行框架
 func (f *PCFrame) Run(reader io.Reader) error {
+	f.resetBarriers(f.skipLines)
+
 	// 启动生产者
 	f.wg.Add(1)
 	go f.producer(reader)
@@ -61,7 +179,9 @@ func (f *PCFrame) producer(reader io.Reader) {
 	defer f.wg.Done()
 	defer close(f.buffer)
 
-	scanner := bufio.NewScanner(reader)
+	quit := make(chan struct{})
+	defer close(quit)
+	scanner := bufio.NewScanner(newStopReader(reader, f.done, quit))
 	// 设置更大的缓冲区 (10MB) 以支持超长特征行
 	// 机器学习数据中，单行可能包含数万个特征
 	const maxScanTokenSize = 10 * 1024 * 1024 // 10MB
@@ -71,33 +191,160 @@ func (f *PCFrame) producer(reader io.Reader) {
 	lineNum := 0
 	batch := make([]string, 0, f.bufSize)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	// 跳过已训练过的行
+	skipper, _ := f.task.(SkipHandler)
+	var skipped []string
+	for lineNum < f.skipLines && scanner.Scan() {
 		lineNum++
+		if skipper == nil {
+			continue
+		}
+		skipped = append(skipped, scanner.Text())
+		if len(skipped) == f.bufSize {
+			f.runSkipped(skipper, skipped)
+			skipped = nil
+		}
+	}
+	if len(skipped) > 0 {
+		f.runSkipped(skipper, skipped)
+	}
+	if f.skipLines > 0 {
+		fmt.Printf("skip %d lines\n", lineNum)
+	}
 
-		batch = append(batch, line)
+	nextLog := (lineNum/f.logNum + 1) * f.logNum
+	lastGroup := ""
+	for scanner.Scan() {
+		if f.Stopped() {
+			break
+		}
+		line := scanner.Text()
 
-		if len(batch) >= f.bufSize {
-			// 发送批次
-			f.buffer <- batch
+		// 分组模式下批次已满时，遇到新分组的第一行才发送批次
+		full := false
+		if f.groupKey != nil {
+			group := f.groupKey(line)
+			full = len(batch) >= f.bufSize && group != lastGroup
+			lastGroup = group
+		}
+		if full && !f.sendBatch(batch, lineNum, &nextLog) {
+			return
+		}
+		if full {
 			batch = make([]string, 0, f.bufSize)
+		}
+
+		lineNum++
+		batch = append(batch, line)
 
-			if lineNum%f.logNum == 0 {
-				fmt.Printf("%d lines finished\n", lineNum)
+		// 按行号对齐批次边界，跳过部分行时进度日志仍按logNum的整数倍输出
+		if f.groupKey == nil && lineNum%f.bufSize == 0 {
+			if !f.sendBatch(batch, lineNum, &nextLog) {
+				return
 			}
+			batch = make([]string, 0, f.bufSize)
 		}
 	}
 
 	// 发送最后一批
+	f.linesRead = lineNum
+	if f.Stopped() {
+		fmt.Printf("stop reading input at %d lines\n", lineNum)
+	}
 	if len(batch) > 0 {
+		f.pending.Add(1)
 		f.buffer <- batch
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := scanner.Err(); err != nil && err != errReadStopped {
 		fmt.Printf("Error reading input: %v\n", err)
 	}
 }
 
+// errReadStopped 调用Stop后stopReader返回的错误
+var errReadStopped = errors.New("input reading stopped")
+
+// stopReader 在单独的goroutine中读取输入，调用Stop后Read立即返回errReadStopped，
+// 输入阻塞（如等待stdin）时生产者仍能响应停止。阻塞中的底层读取不会被打断，goroutine在读取返回后退出
+type stopReader struct {
+	results chan readResult
+	done    <-chan struct{}
+	buf     []byte
+	err     error
+}
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// newStopReader 创建stopReader并开始读取，done关闭时Read返回errReadStopped，quit关闭时后台goroutine退出
+func newStopReader(r io.Reader, done, quit <-chan struct{}) *stopReader {
+	sr := &stopReader{results: make(chan readResult, 1), done: done}
+	go func() {
+		for {
+			buf := make([]byte, 64*1024)
+			n, err := r.Read(buf)
+			select {
+			case sr.results <- readResult{buf[:n], err}:
+			case <-quit:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return sr
+}
+
+func (r *stopReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 && r.err == nil {
+		select {
+		case <-r.done:
+			return 0, errReadStopped
+		case res := <-r.results:
+			r.buf, r.err = res.data, res.err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	if len(r.buf) == 0 && r.err != nil {
+		return n, r.err
+	}
+	return n, nil
+}
+
+// runSkipped 在生产者线程中处理一批跳过的行，此时还没有发送任何批次
+func (f *PCFrame) runSkipped(skipper SkipHandler, batch []string) {
+	if err := skipper.SkipTask(batch); err != nil {
+		fmt.Printf("Error processing skipped lines: %v\n", err)
+	}
+}
+
+// sendBatch 发送批次，输出进度并检查暂停点，lineNum为包含该批次在内已读取的行数
+// 返回false表示停止读取输入
+func (f *PCFrame) sendBatch(batch []string, lineNum int, nextLog *int) bool {
+	f.pending.Add(1)
+	f.buffer <- batch
+
+	if lineNum >= *nextLog {
+		*nextLog = (lineNum/f.logNum + 1) * f.logNum
+		if reporter, ok := f.task.(ProgressReporter); ok {
+			fmt.Printf("%d lines finished, %s\n", lineNum, reporter.Progress())
+		} else {
+			fmt.Printf("%d lines finished\n", lineNum)
+		}
+	}
+
+	if !f.checkBarriers(lineNum) {
+		f.linesRead = lineNum
+		fmt.Printf("stop reading input at %d lines\n", lineNum)
+		return false
+	}
+	return true
+}
+
 // consumer 消费者线程
 func (f *PCFrame) consumer() {
 	defer f.wg.Done()
@@ -106,6 +353,7 @@ func (f *PCFrame) consumer() {
 		if err := f.task.RunTask(batch); err != nil {
 			fmt.Printf("Error processing batch: %v\n", err)
 		}
+		f.pending.Done()
 	}
 }
 
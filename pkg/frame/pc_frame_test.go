@@ -0,0 +1,184 @@
+package frame
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countTask struct {
+	lines int64
+}
+
+func (t *countTask) RunTask(dataBuffer []string) error {
+	atomic.AddInt64(&t.lines, int64(len(dataBuffer)))
+	return nil
+}
+
+func genInput(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "1 f%d:1\n", i)
+	}
+	return sb.String()
+}
+
+func TestBarrierSeesAllBatchesDone(t *testing.T) {
+	task := &countTask{}
+	f := NewPCFrame()
+	f.Init(task, 4)
+
+	var seen []int
+	f.AddBarrier(12000, func(lineNum int) bool {
+		// 暂停点处之前发送的批次都已处理完
+		if got := atomic.LoadInt64(&task.lines); got != int64(lineNum) {
+			t.Errorf("barrier at %d lines, but %d lines processed", lineNum, got)
+		}
+		seen = append(seen, lineNum)
+		return lineNum < 30000
+	})
+	if err := f.Run(strings.NewReader(genInput(50000))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 批次边界为5000的整数倍，第二次在25000行触发，第三次在40000行触发后停止
+	if fmt.Sprint(seen) != "[15000 25000 40000]" {
+		t.Fatalf("barriers at %v", seen)
+	}
+	if task.lines != 40000 || f.LinesRead() != 40000 {
+		t.Fatalf("processed %d lines, read %d lines", task.lines, f.LinesRead())
+	}
+}
+
+func TestStopAndSkip(t *testing.T) {
+	task := &countTask{}
+	f := NewPCFrame()
+	f.Init(task, 2)
+	f.SetSkipLines(7000)
+
+	var once sync.Once
+	f.AddBarrier(5000, func(lineNum int) bool {
+		once.Do(f.Stop)
+		return true
+	})
+	if err := f.Run(strings.NewReader(genInput(50000))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 跳过7000行，在10000行的批次边界停止，跳过的行不处理
+	if !f.Stopped() || f.LinesRead() != 10000 || task.lines != 3000 {
+		t.Fatalf("stopped=%v, read %d lines, processed %d lines", f.Stopped(), f.LinesRead(), task.lines)
+	}
+}
+
+// skipTask 记录跳过的行
+type skipTask struct {
+	countTask
+	skipped []string
+}
+
+func (t *skipTask) SkipTask(dataBuffer []string) error {
+	t.skipped = append(t.skipped, dataBuffer...)
+	return nil
+}
+
+func TestSkipHandler(t *testing.T) {
+	task := &skipTask{}
+	f := NewPCFrame()
+	f.Init(task, 2)
+	f.SetSkipLines(12000)
+	if err := f.Run(strings.NewReader(genInput(20000))); err != nil {
+		t.Fatal(err)
+	}
+	// 跳过的行按输入顺序交给SkipTask，其余的行正常处理
+	if len(task.skipped) != 12000 || task.skipped[0] != "1 f0:1" || task.skipped[11999] != "1 f11999:1" || task.lines != 8000 {
+		t.Fatalf("skipped %d lines, processed %d lines", len(task.skipped), task.lines)
+	}
+}
+
+type groupTask struct {
+	mu     sync.Mutex
+	groups map[string]int // 分组键出现在几个批次中
+}
+
+func (t *groupTask) RunTask(dataBuffer []string) error {
+	seen := make(map[string]bool)
+	for _, line := range dataBuffer {
+		seen[strings.Fields(line)[0]] = true
+	}
+	t.mu.Lock()
+	for g := range seen {
+		t.groups[g]++
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+func TestGroupKeyKeepsGroupsInOneBatch(t *testing.T) {
+	// 每个分组7行，分组边界与5000行的批次边界不对齐
+	var sb strings.Builder
+	for i := 0; i < 30000; i++ {
+		fmt.Fprintf(&sb, "q%d 1 f%d:1\n", i/7, i)
+	}
+	task := &groupTask{groups: make(map[string]int)}
+	f := NewPCFrame()
+	f.Init(task, 3)
+	f.SetGroupKey(func(line string) string {
+		return strings.Fields(line)[0]
+	})
+
+	var seen []int
+	f.AddBarrier(10000, func(lineNum int) bool {
+		seen = append(seen, lineNum)
+		return true
+	})
+	if err := f.Run(strings.NewReader(sb.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(task.groups) != (30000+6)/7 {
+		t.Fatalf("%d groups processed", len(task.groups))
+	}
+	for g, n := range task.groups {
+		if n != 1 {
+			t.Fatalf("group %s split into %d batches", g, n)
+		}
+	}
+	// 批次延续到分组结束（5005, 10010, 15015, ...），暂停点落在分组边界上
+	if fmt.Sprint(seen) != "[10010 20020]" {
+		t.Fatalf("barriers at %v", seen)
+	}
+}
+
+func TestStopBlockedReader(t *testing.T) {
+	task := &countTask{}
+	f := NewPCFrame()
+	f.Init(task, 2)
+
+	// 写入12000行后管道保持打开，生产者阻塞在读取上
+	r, w := io.Pipe()
+	defer w.Close()
+	go io.WriteString(w, genInput(12000))
+
+	done := make(chan struct{})
+	go func() {
+		f.Run(r)
+		close(done)
+	}()
+	for atomic.LoadInt64(&task.lines) < 10000 {
+		time.Sleep(time.Millisecond)
+	}
+	f.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Stop while the reader was blocked")
+	}
+	if task.lines != int64(f.LinesRead()) || f.LinesRead() < 10000 {
+		t.Fatalf("read %d lines, processed %d lines", f.LinesRead(), task.lines)
+	}
+}
@@ -0,0 +1,91 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// errStopped 暂停点要求停止回放
+var errStopped = errors.New("replay stopped")
+
+// SampleTask 处理已解析样本的任务（多轮训练回放时使用）
+type SampleTask interface {
+	RunSamples(batch []*sample.FMSample) error
+}
+
+// Replay 按order的顺序（为nil时按写入顺序）回放缓存中的样本
+// 生产者从缓存中读取批次，threadNum个消费者调用task.RunSamples处理，task必须实现SampleTask
+func (f *PCFrame) Replay(cache *sample.SampleCache, order []int) error {
+	task, ok := f.task.(SampleTask)
+	if !ok {
+		return fmt.Errorf("task does not support sample replay")
+	}
+
+	f.resetBarriers(0)
+	buffer := make(chan []*sample.FMSample, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < f.threadNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range buffer {
+				if err := task.RunSamples(batch); err != nil {
+					fmt.Printf("Error processing batch: %v\n", err)
+				}
+				f.pending.Done()
+			}
+		}()
+	}
+
+	sampleNum := 0
+	nextLog := f.logNum
+	f.replayed = 0
+	send := func(batch []*sample.FMSample) error {
+		f.pending.Add(1)
+		buffer <- batch
+		sampleNum += len(batch)
+		if sampleNum >= nextLog {
+			nextLog = (sampleNum/f.logNum + 1) * f.logNum
+			fmt.Printf("%d lines finished\n", sampleNum)
+		}
+		f.replayed = sampleNum
+		if !f.checkBarriers(sampleNum) || f.Stopped() {
+			return errStopped
+		}
+		return nil
+	}
+
+	// 分组模式下每批末尾的分组可能延续到下一批，留到下一批一起发送
+	var carry []*sample.FMSample
+	err := cache.Batches(order, f.bufSize, func(batch []*sample.FMSample) error {
+		if f.groupKey == nil {
+			return send(batch)
+		}
+		batch = append(carry, batch...)
+		last := batch[len(batch)-1].Group
+		cut := len(batch)
+		for cut > 0 && batch[cut-1].Group == last {
+			cut--
+		}
+		if cut == 0 {
+			carry = batch
+			return nil
+		}
+		carry = append([]*sample.FMSample(nil), batch[cut:]...)
+		return send(batch[:cut])
+	})
+	if err == nil && len(carry) > 0 {
+		err = send(carry)
+	}
+	close(buffer)
+	if err == errStopped {
+		fmt.Printf("stop replaying at %d lines\n", sampleNum)
+		err = nil
+	}
+
+	wg.Wait()
+	return err
+}
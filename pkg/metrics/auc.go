@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"sort"
+)
+
+// AUC 流式AUC计算
+type AUC interface {
+	// Add 添加一个样本，label为0或1
+	Add(score, label, weight float64)
+	// Merge 合并另一个同类型的AUC累加器
+	Merge(other AUC)
+	// Value 计算AUC，正样本或负样本为空时返回0.5
+	Value() float64
+	// Reset 清空
+	Reset()
+}
+
+// NewAUC 创建AUC累加器，bins>0时使用直方图近似，否则精确排序
+func NewAUC(bins int) AUC {
+	if bins > 0 {
+		return NewHistogramAUC(bins)
+	}
+	return NewExactAUC()
+}
+
+// scoredLabel 带权重的打分样本
+type scoredLabel struct {
+	score  float64
+	label  float64
+	weight float64
+}
+
+// ExactAUC 保存全部打分并排序计算的精确AUC（处理相同打分）
+type ExactAUC struct {
+	items []scoredLabel
+}
+
+// NewExactAUC 创建精确AUC
+func NewExactAUC() *ExactAUC {
+	return &ExactAUC{}
+}
+
+// Add 添加一个样本
+func (a *ExactAUC) Add(score, label, weight float64) {
+	a.items = append(a.items, scoredLabel{score: score, label: label, weight: weight})
+}
+
+// Merge 合并
+func (a *ExactAUC) Merge(other AUC) {
+	a.items = append(a.items, other.(*ExactAUC).items...)
+}
+
+// Value 计算AUC
+func (a *ExactAUC) Value() float64 {
+	return exactAUC(a.items)
+}
+
+// Reset 清空
+func (a *ExactAUC) Reset() {
+	a.items = a.items[:0]
+}
+
+// exactAUC 按打分升序扫描，相同打分的正负样本对计0.5
+func exactAUC(items []scoredLabel) float64 {
+	sorted := make([]scoredLabel, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].score < sorted[j].score })
+
+	var area, posTotal, negTotal float64
+	for i := 0; i < len(sorted); {
+		j := i
+		var pos, neg float64
+		for ; j < len(sorted) && sorted[j].score == sorted[i].score; j++ {
+			if sorted[j].label > 0.5 {
+				pos += sorted[j].weight
+			} else {
+				neg += sorted[j].weight
+			}
+		}
+		area += pos * (negTotal + 0.5*neg)
+		posTotal += pos
+		negTotal += neg
+		i = j
+	}
+
+	if posTotal == 0 || negTotal == 0 {
+		return 0.5
+	}
+	return area / (posTotal * negTotal)
+}
+
+// HistogramAUC 把[0,1]的打分等分为bins个桶统计正负样本，内存固定，适合海量样本
+type HistogramAUC struct {
+	pos []float64
+	neg []float64
+}
+
+// NewHistogramAUC 创建直方图AUC
+func NewHistogramAUC(bins int) *HistogramAUC {
+	return &HistogramAUC{
+		pos: make([]float64, bins),
+		neg: make([]float64, bins),
+	}
+}
+
+// bucket 计算打分所在的桶，超出[0,1]的打分落在两端
+func (a *HistogramAUC) bucket(score float64) int {
+	b := int(score * float64(len(a.pos)))
+	if b < 0 {
+		return 0
+	}
+	if b >= len(a.pos) {
+		return len(a.pos) - 1
+	}
+	return b
+}
+
+// Add 添加一个样本
+func (a *HistogramAUC) Add(score, label, weight float64) {
+	b := a.bucket(score)
+	if label > 0.5 {
+		a.pos[b] += weight
+	} else {
+		a.neg[b] += weight
+	}
+}
+
+// Merge 合并
+func (a *HistogramAUC) Merge(other AUC) {
+	o := other.(*HistogramAUC)
+	for b := range a.pos {
+		a.pos[b] += o.pos[b]
+		a.neg[b] += o.neg[b]
+	}
+}
+
+// Value 计算AUC，同一个桶内的正负样本对计0.5
+func (a *HistogramAUC) Value() float64 {
+	var area, posTotal, negTotal float64
+	for b := range a.pos {
+		area += a.pos[b] * (negTotal + 0.5*a.neg[b])
+		posTotal += a.pos[b]
+		negTotal += a.neg[b]
+	}
+	if posTotal == 0 || negTotal == 0 {
+		return 0.5
+	}
+	return area / (posTotal * negTotal)
+}
+
+// Reset 清空
+func (a *HistogramAUC) Reset() {
+	for b := range a.pos {
+		a.pos[b] = 0
+		a.neg[b] = 0
+	}
+}
@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// logLossEps 计算logloss时对概率的截断
+const logLossEps = 1e-15
+
+// EvaluatorOption 评估选项
+type EvaluatorOption struct {
+	AUCBins        int  // 直方图AUC的桶数，0表示精确AUC
+	CalibrationNum int  // 校准表按打分等分的桶数
+	Group          bool // 是否按分组键计算GAUC
+	NDCGK          int  // 分组NDCG@k的k，0表示不计算
+	Regression     bool // 实数标签的回归评估，只计算MSE和均值，不计算AUC、logloss和校准表
+	ClassNum       int  // 多分类评估的类别数，只计算logloss和准确率，0表示二分类
+}
+
+// NewEvaluatorOption 创建默认评估选项
+func NewEvaluatorOption() *EvaluatorOption {
+	return &EvaluatorOption{
+		AUCBins:        0,
+		CalibrationNum: 10,
+	}
+}
+
+// Evaluator 二分类流式评估器（非并发安全，多线程时每个线程各自累加后Merge）
+// 回归模式下label为实数标签，PredCTR、ActualCTR分别为预测值和标签的加权均值
+// 多分类模式下通过AddClass添加样本
+type Evaluator struct {
+	opt       *EvaluatorOption
+	auc       AUC
+	count     int64
+	weightSum float64
+	posSum    float64 // 正样本权重和
+	predSum   float64 // 预测概率加权和
+	logLoss   float64
+	sqErr     float64
+	correct   float64 // 多分类预测正确的样本权重和
+	calib     []CalibrationBucket
+	groups    *GroupAUC
+}
+
+// CalibrationBucket 校准表的一个打分区间
+type CalibrationBucket struct {
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+	Count     int64   `json:"count"`
+	Weight    float64 `json:"weight"`
+	PredCTR   float64 `json:"pred_ctr"`
+	ActualCTR float64 `json:"actual_ctr"`
+	predSum   float64
+	posSum    float64
+}
+
+// Summary 评估结果
+type Summary struct {
+	Count       int64               `json:"count"`
+	Weight      float64             `json:"weight"`
+	Positives   float64             `json:"positives"`
+	AUC         float64             `json:"auc"`
+	LogLoss     float64             `json:"logloss"`
+	MSE         float64             `json:"mse"`
+	PredCTR     float64             `json:"pred_ctr"`
+	ActualCTR   float64             `json:"actual_ctr"`
+	CTRRatio    float64             `json:"ctr_ratio"`
+	Calibration []CalibrationBucket `json:"calibration"`
+	Group       *GroupSummary       `json:"group,omitempty"`
+	Regression  bool                `json:"regression,omitempty"`
+	ClassNum    int                 `json:"class_num,omitempty"`
+	Accuracy    float64             `json:"accuracy,omitempty"`
+}
+
+// NewEvaluator 创建评估器
+func NewEvaluator(opt *EvaluatorOption) *Evaluator {
+	e := &Evaluator{opt: opt}
+	if opt.Regression || opt.ClassNum > 0 { // 分组指标只支持二分类
+		return e
+	}
+	e.auc = NewAUC(opt.AUCBins)
+	e.calib = make([]CalibrationBucket, opt.CalibrationNum)
+	for b := range e.calib {
+		e.calib[b].Lower = float64(b) / float64(opt.CalibrationNum)
+		e.calib[b].Upper = float64(b+1) / float64(opt.CalibrationNum)
+	}
+	if opt.Group {
+		e.groups = NewGroupAUC(opt.NDCGK)
+	}
+	return e
+}
+
+// Add 添加一个样本，p为预测概率，label为0或1（回归模式下为预测值和实数标签），weight为样本权重
+func (e *Evaluator) Add(p, label, weight float64) {
+	e.add(p, label, weight)
+}
+
+// AddGroup 添加一个带分组键的样本，group为空表示样本缺少分组键
+func (e *Evaluator) AddGroup(group string, p, label, weight float64) {
+	e.add(p, label, weight)
+	if e.groups != nil {
+		e.groups.Add(group, p, label, weight)
+	}
+}
+
+func (e *Evaluator) add(p, label, weight float64) {
+	e.count++
+	e.weightSum += weight
+	e.posSum += label * weight
+	e.predSum += p * weight
+	e.sqErr += weight * (p - label) * (p - label)
+	if e.opt.Regression {
+		return
+	}
+
+	e.auc.Add(p, label, weight)
+
+	q := math.Min(math.Max(p, logLossEps), 1.0-logLossEps)
+	e.logLoss -= weight * (label*math.Log(q) + (1.0-label)*math.Log(1.0-q))
+
+	if len(e.calib) > 0 {
+		b := int(p * float64(len(e.calib)))
+		if b < 0 {
+			b = 0
+		} else if b >= len(e.calib) {
+			b = len(e.calib) - 1
+		}
+		e.calib[b].Count++
+		e.calib[b].Weight += weight
+		e.calib[b].predSum += p * weight
+		e.calib[b].posSum += label * weight
+	}
+}
+
+// AddClass 添加一个多分类样本，probs为各类别的预测概率，label为类别序号
+func (e *Evaluator) AddClass(probs []float64, label int, weight float64) {
+	e.count++
+	e.weightSum += weight
+	q := math.Max(probs[label], logLossEps)
+	e.logLoss -= weight * math.Log(q)
+	best := 0
+	for c, p := range probs {
+		if p > probs[best] {
+			best = c
+		}
+	}
+	if best == label {
+		e.correct += weight
+	}
+}
+
+// Merge 合并另一个评估器（选项必须相同）
+func (e *Evaluator) Merge(other *Evaluator) {
+	if e.auc != nil {
+		e.auc.Merge(other.auc)
+	}
+	e.count += other.count
+	e.weightSum += other.weightSum
+	e.posSum += other.posSum
+	e.predSum += other.predSum
+	e.logLoss += other.logLoss
+	e.sqErr += other.sqErr
+	e.correct += other.correct
+	for b := range e.calib {
+		e.calib[b].Count += other.calib[b].Count
+		e.calib[b].Weight += other.calib[b].Weight
+		e.calib[b].predSum += other.calib[b].predSum
+		e.calib[b].posSum += other.calib[b].posSum
+	}
+	if e.groups != nil {
+		e.groups.Merge(other.groups)
+	}
+}
+
+// Count 样本数
+func (e *Evaluator) Count() int64 {
+	return e.count
+}
+
+// Summary 计算评估结果
+func (e *Evaluator) Summary() *Summary {
+	s := &Summary{
+		Count:       e.count,
+		Weight:      e.weightSum,
+		Calibration: make([]CalibrationBucket, len(e.calib)),
+		Regression:  e.opt.Regression,
+		ClassNum:    e.opt.ClassNum,
+	}
+	if e.auc != nil {
+		s.AUC = e.auc.Value()
+		s.Positives = e.posSum
+	}
+	if e.weightSum > 0 {
+		s.LogLoss = e.logLoss / e.weightSum
+		s.MSE = e.sqErr / e.weightSum
+		s.PredCTR = e.predSum / e.weightSum
+		s.ActualCTR = e.posSum / e.weightSum
+	}
+	if e.opt.ClassNum > 0 && e.weightSum > 0 {
+		s.Accuracy = e.correct / e.weightSum
+	}
+	if e.posSum > 0 && !e.opt.Regression {
+		s.CTRRatio = e.predSum / e.posSum
+	}
+	for b, c := range e.calib {
+		if c.Weight > 0 {
+			c.PredCTR = c.predSum / c.Weight
+			c.ActualCTR = c.posSum / c.Weight
+		}
+		s.Calibration[b] = c
+	}
+	if e.groups != nil {
+		s.Group = e.groups.Summary()
+	}
+	return s
+}
+
+// Text 文本格式的评估结果
+func (s *Summary) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "count: %d\n", s.Count)
+	if s.Weight != float64(s.Count) {
+		fmt.Fprintf(&sb, "weight: %.6g\n", s.Weight)
+	}
+	if s.Regression {
+		fmt.Fprintf(&sb, "mse: %.6f\n", s.MSE)
+		fmt.Fprintf(&sb, "rmse: %.6f\n", math.Sqrt(s.MSE))
+		fmt.Fprintf(&sb, "pred_mean: %.6f\n", s.PredCTR)
+		fmt.Fprintf(&sb, "label_mean: %.6f\n", s.ActualCTR)
+		return sb.String()
+	}
+	if s.ClassNum > 0 {
+		fmt.Fprintf(&sb, "classes: %d\n", s.ClassNum)
+		fmt.Fprintf(&sb, "logloss: %.6f\n", s.LogLoss)
+		fmt.Fprintf(&sb, "accuracy: %.6f\n", s.Accuracy)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "positives: %.6g\n", s.Positives)
+	fmt.Fprintf(&sb, "auc: %.6f\n", s.AUC)
+	fmt.Fprintf(&sb, "logloss: %.6f\n", s.LogLoss)
+	fmt.Fprintf(&sb, "mse: %.6f\n", s.MSE)
+	fmt.Fprintf(&sb, "pred_ctr: %.6f\n", s.PredCTR)
+	fmt.Fprintf(&sb, "actual_ctr: %.6f\n", s.ActualCTR)
+	fmt.Fprintf(&sb, "ctr_ratio: %.6f\n", s.CTRRatio)
+	if s.Group != nil {
+		fmt.Fprintf(&sb, "groups: %d (valid: %d, samples without group: %d)\n",
+			s.Group.GroupNum, s.Group.ValidGroupNum, s.Group.MissingNum)
+		fmt.Fprintf(&sb, "gauc: %.6f\n", s.Group.GAUC)
+		if s.Group.NDCGK > 0 {
+			fmt.Fprintf(&sb, "ndcg@%d: %.6f\n", s.Group.NDCGK, s.Group.NDCG)
+		}
+	}
+	if len(s.Calibration) > 0 {
+		fmt.Fprintf(&sb, "calibration:\n")
+		fmt.Fprintf(&sb, "  %-15s %10s %10s %10s %10s\n", "score", "count", "pred_ctr", "actual_ctr", "ratio")
+		for _, c := range s.Calibration {
+			if c.Count == 0 {
+				continue
+			}
+			ratio := 0.0
+			if c.ActualCTR > 0 {
+				ratio = c.PredCTR / c.ActualCTR
+			}
+			fmt.Fprintf(&sb, "  [%.3f, %.3f) %10d %10.6f %10.6f %10.4f\n",
+				c.Lower, c.Upper, c.Count, c.PredCTR, c.ActualCTR, ratio)
+		}
+	}
+	return sb.String()
+}
+
+// JSON JSON格式的评估结果
+func (s *Summary) JSON() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(data)
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// GroupSummary 分组评估结果
+type GroupSummary struct {
+	GroupNum      int     `json:"group_num"`       // 分组总数
+	ValidGroupNum int     `json:"valid_group_num"` // 同时包含正负样本的分组数
+	MissingNum    int64   `json:"missing_num"`     // 没有分组键的样本数
+	GAUC          float64 `json:"gauc"`
+	NDCGK         int     `json:"ndcg_k,omitempty"`
+	NDCG          float64 `json:"ndcg,omitempty"`
+}
+
+// GroupAUC 按分组键（用户、会话等）计算GAUC和NDCG@k
+// GAUC为各分组AUC按分组样本权重加权的平均，只有正样本或只有负样本的分组不参与计算
+type GroupAUC struct {
+	ndcgK   int
+	groups  map[string][]scoredLabel
+	missing int64
+}
+
+// NewGroupAUC 创建分组AUC，ndcgK>0时同时计算NDCG@k
+func NewGroupAUC(ndcgK int) *GroupAUC {
+	return &GroupAUC{
+		ndcgK:  ndcgK,
+		groups: make(map[string][]scoredLabel),
+	}
+}
+
+// Add 添加一个样本，group为空表示样本没有分组键
+func (g *GroupAUC) Add(group string, score, label, weight float64) {
+	if group == "" {
+		g.missing++
+		return
+	}
+	g.groups[group] = append(g.groups[group], scoredLabel{score: score, label: label, weight: weight})
+}
+
+// Merge 合并另一个分组AUC，同一分组的样本合并在一起
+func (g *GroupAUC) Merge(other *GroupAUC) {
+	for key, items := range other.groups {
+		g.groups[key] = append(g.groups[key], items...)
+	}
+	g.missing += other.missing
+}
+
+// Summary 计算分组评估结果
+func (g *GroupAUC) Summary() *GroupSummary {
+	s := &GroupSummary{
+		GroupNum:   len(g.groups),
+		MissingNum: g.missing,
+		NDCGK:      g.ndcgK,
+	}
+
+	var aucSum, weightSum, ndcgSum float64
+	for _, items := range g.groups {
+		var pos, neg float64
+		for _, item := range items {
+			if item.label > 0.5 {
+				pos += item.weight
+			} else {
+				neg += item.weight
+			}
+		}
+		if pos == 0 || neg == 0 {
+			continue
+		}
+		s.ValidGroupNum++
+		aucSum += exactAUC(items) * (pos + neg)
+		weightSum += pos + neg
+		if g.ndcgK > 0 {
+			ndcgSum += ndcgAtK(items, g.ndcgK)
+		}
+	}
+
+	if weightSum > 0 {
+		s.GAUC = aucSum / weightSum
+	}
+	if s.ValidGroupNum > 0 {
+		s.NDCG = ndcgSum / float64(s.ValidGroupNum)
+	}
+	return s
+}
+
+// ndcgAtK 以标签为相关度计算NDCG@k，相同打分时按标签升序排列（悲观估计）
+func ndcgAtK(items []scoredLabel, k int) float64 {
+	ranked := make([]scoredLabel, len(items))
+	copy(ranked, items)
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].label < ranked[j].label
+	})
+	ideal := make([]scoredLabel, len(items))
+	copy(ideal, items)
+	sort.Slice(ideal, func(i, j int) bool { return ideal[i].label > ideal[j].label })
+
+	idcg := dcgAtK(ideal, k)
+	if idcg == 0 {
+		return 0
+	}
+	return dcgAtK(ranked, k) / idcg
+}
+
+func dcgAtK(ranked []scoredLabel, k int) float64 {
+	dcg := 0.0
+	for i := 0; i < k && i < len(ranked); i++ {
+		dcg += (math.Pow(2, ranked[i].label) - 1) / math.Log2(float64(i+2))
+	}
+	return dcg
+}
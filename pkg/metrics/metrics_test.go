@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestExactAUC(t *testing.T) {
+	auc := NewAUC(0)
+	// 3个正样本、3个负样本，其中一对打分相同
+	scores := []float64{0.9, 0.8, 0.5, 0.5, 0.3, 0.1}
+	labels := []float64{1, 1, 1, 0, 0, 0}
+	for i := range scores {
+		auc.Add(scores[i], labels[i], 1.0)
+	}
+	// 9个正负样本对中8对正确排序，1对相同打分计0.5
+	if got, want := auc.Value(), 8.5/9.0; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("exact auc = %v, want %v", got, want)
+	}
+
+	empty := NewAUC(0)
+	empty.Add(0.3, 1, 1)
+	if empty.Value() != 0.5 {
+		t.Fatalf("auc without negatives = %v, want 0.5", empty.Value())
+	}
+}
+
+func TestHistogramAUCCloseToExact(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	exact := NewAUC(0)
+	hist := NewAUC(10000)
+	for i := 0; i < 20000; i++ {
+		label := 0.0
+		if r.Float64() < 0.3 {
+			label = 1.0
+		}
+		score := 1.0 / (1.0 + math.Exp(-(label*1.5 + r.NormFloat64())))
+		exact.Add(score, label, 1.0)
+		hist.Add(score, label, 1.0)
+	}
+	if diff := math.Abs(exact.Value() - hist.Value()); diff > 1e-3 {
+		t.Fatalf("histogram auc %v differs from exact auc %v", hist.Value(), exact.Value())
+	}
+}
+
+func TestEvaluatorWeights(t *testing.T) {
+	opt := NewEvaluatorOption()
+	weighted := NewEvaluator(opt)
+	repeated := NewEvaluator(opt)
+
+	// 权重为2的样本与重复两次的样本等价
+	preds := []float64{0.2, 0.6, 0.7, 0.4}
+	labels := []float64{0, 1, 0, 1}
+	weights := []float64{2, 1, 2, 1}
+	for i := range preds {
+		weighted.Add(preds[i], labels[i], weights[i])
+		for j := 0; j < int(weights[i]); j++ {
+			repeated.Add(preds[i], labels[i], 1)
+		}
+	}
+	a, b := weighted.Summary(), repeated.Summary()
+	if a.Count != 4 || a.Weight != 6 || b.Weight != 6 {
+		t.Fatalf("count = %d, weight = %v", a.Count, a.Weight)
+	}
+	if math.Abs(a.AUC-b.AUC) > 1e-12 || math.Abs(a.LogLoss-b.LogLoss) > 1e-12 || math.Abs(a.PredCTR-b.PredCTR) > 1e-12 {
+		t.Fatalf("weighted summary %+v differs from %+v", a, b)
+	}
+
+	v := NewProgressiveValidator(10)
+	v.AddBatch(preds, labels, weights)
+	r := v.Report()
+	if r.Weight != 6 || math.Abs(r.LogLoss-b.LogLoss) > 1e-12 || math.Abs(r.WindowAUC-b.AUC) > 1e-12 {
+		t.Fatalf("progressive report = %+v", r)
+	}
+}
+
+func TestEvaluatorRegression(t *testing.T) {
+	opt := NewEvaluatorOption()
+	opt.Regression = true
+	e := NewEvaluator(opt)
+	other := NewEvaluator(opt)
+	e.Add(2.5, 3, 1)
+	other.Add(1, 0, 2)
+	e.Merge(other)
+
+	s := e.Summary()
+	if !s.Regression || s.Count != 2 || len(s.Calibration) != 0 {
+		t.Fatalf("summary = %+v", s)
+	}
+	// mse = (0.25 + 2*1) / 3, 预测均值 = (2.5 + 2*1) / 3, 标签均值 = 3 / 3
+	if math.Abs(s.MSE-0.75) > 1e-12 || math.Abs(s.PredCTR-1.5) > 1e-12 || math.Abs(s.ActualCTR-1) > 1e-12 {
+		t.Fatalf("summary = %+v", s)
+	}
+	if !strings.Contains(s.Text(), "rmse:") || strings.Contains(s.Text(), "auc:") {
+		t.Fatalf("regression text:\n%s", s.Text())
+	}
+}
+
+func TestEvaluatorMulticlass(t *testing.T) {
+	opt := NewEvaluatorOption()
+	opt.ClassNum = 3
+	e := NewEvaluator(opt)
+	other := NewEvaluator(opt)
+	e.AddClass([]float64{0.5, 0.25, 0.25}, 0, 1)
+	other.AddClass([]float64{0.5, 0.25, 0.25}, 1, 3)
+	e.Merge(other)
+
+	s := e.Summary()
+	// logloss = (log2 + 3*log4) / 4，第二个样本预测错误
+	if s.ClassNum != 3 || math.Abs(s.LogLoss-7*math.Log(2)/4) > 1e-12 || s.Accuracy != 0.25 {
+		t.Fatalf("summary = %+v", s)
+	}
+	if !strings.Contains(s.Text(), "accuracy:") || strings.Contains(s.Text(), "auc:") {
+		t.Fatalf("multiclass text:\n%s", s.Text())
+	}
+}
+
+func TestEvaluatorMerge(t *testing.T) {
+	opt := NewEvaluatorOption()
+	opt.CalibrationNum = 4
+	all := NewEvaluator(opt)
+	parts := []*Evaluator{NewEvaluator(opt), NewEvaluator(opt)}
+
+	preds := []float64{0.1, 0.4, 0.6, 0.9, 0.2, 0.7}
+	labels := []float64{0, 0, 1, 1, 1, 0}
+	for i := range preds {
+		all.Add(preds[i], labels[i], 1)
+		parts[i%2].Add(preds[i], labels[i], 1)
+	}
+	parts[0].Merge(parts[1])
+
+	a, b := all.Summary(), parts[0].Summary()
+	if a.Count != 6 || a.Positives != 3 {
+		t.Fatalf("count = %d, positives = %v", a.Count, a.Positives)
+	}
+	if math.Abs(a.AUC-b.AUC) > 1e-12 || math.Abs(a.LogLoss-b.LogLoss) > 1e-12 || math.Abs(a.MSE-b.MSE) > 1e-12 {
+		t.Fatalf("merged summary %+v differs from %+v", b, a)
+	}
+
+	wantLogLoss := 0.0
+	wantMSE := 0.0
+	for i := range preds {
+		p, y := preds[i], labels[i]
+		wantLogLoss -= y*math.Log(p) + (1-y)*math.Log(1-p)
+		wantMSE += (p - y) * (p - y)
+	}
+	if math.Abs(a.LogLoss-wantLogLoss/6) > 1e-12 || math.Abs(a.MSE-wantMSE/6) > 1e-12 {
+		t.Fatalf("logloss = %v, mse = %v", a.LogLoss, a.MSE)
+	}
+	if math.Abs(a.CTRRatio-2.9/3.0) > 1e-12 {
+		t.Fatalf("ctr ratio = %v", a.CTRRatio)
+	}
+
+	// 校准表: [0,0.25) 0.1 0.2; [0.25,0.5) 0.4; [0.5,0.75) 0.6 0.7; [0.75,1] 0.9
+	counts := []int64{2, 1, 2, 1}
+	for i, c := range a.Calibration {
+		if c.Count != counts[i] {
+			t.Fatalf("calibration bucket %d count = %d, want %d", i, c.Count, counts[i])
+		}
+	}
+	if math.Abs(a.Calibration[0].ActualCTR-0.5) > 1e-12 {
+		t.Fatalf("calibration bucket 0 actual ctr = %v", a.Calibration[0].ActualCTR)
+	}
+
+	var decoded Summary
+	if err := json.Unmarshal([]byte(a.JSON()), &decoded); err != nil {
+		t.Fatalf("unmarshal summary json: %v", err)
+	}
+	if decoded.Count != a.Count || len(decoded.Calibration) != 4 {
+		t.Fatalf("decoded summary = %+v", decoded)
+	}
+}
+
+func TestGroupAUC(t *testing.T) {
+	g := NewGroupAUC(2)
+	// u1: AUC=1，4条样本
+	g.Add("u1", 0.9, 1, 1)
+	g.Add("u1", 0.8, 1, 1)
+	g.Add("u1", 0.3, 0, 1)
+	g.Add("u1", 0.2, 0, 1)
+	// u2: AUC=0，2条样本
+	g.Add("u2", 0.1, 1, 1)
+	g.Add("u2", 0.7, 0, 1)
+	// u3只有正样本，u4只有负样本，不参与计算
+	g.Add("u3", 0.5, 1, 1)
+	g.Add("u4", 0.5, 0, 1)
+	g.Add("", 0.5, 1, 1)
+
+	other := NewGroupAUC(2)
+	other.Add("u3", 0.4, 1, 1)
+	g.Merge(other)
+
+	s := g.Summary()
+	if s.GroupNum != 4 || s.ValidGroupNum != 2 || s.MissingNum != 1 {
+		t.Fatalf("group summary = %+v", s)
+	}
+	if want := (1.0*4 + 0.0*2) / 6; math.Abs(s.GAUC-want) > 1e-12 {
+		t.Fatalf("gauc = %v, want %v", s.GAUC, want)
+	}
+	// u1的NDCG@2为1；u2排在首位的是负样本，NDCG@2 = (1/log2(3)) / 1
+	if want := (1.0 + 1.0/math.Log2(3)) / 2; math.Abs(s.NDCG-want) > 1e-12 {
+		t.Fatalf("ndcg = %v, want %v", s.NDCG, want)
+	}
+}
+
+func TestProgressiveValidatorWindow(t *testing.T) {
+	v := NewProgressiveValidator(4)
+	// 前4个样本排序完全错误，后4个完全正确，窗口只保留后4个
+	v.AddBatch([]float64{0.9, 0.8, 0.2, 0.1}, []float64{0, 0, 1, 1}, nil)
+	v.AddBatch([]float64{0.9, 0.8}, []float64{1, 1}, nil)
+	v.AddBatch([]float64{0.2, 0.1}, []float64{0, 0}, nil)
+
+	r := v.Report()
+	if r.Count != 8 || r.WindowSize != 4 {
+		t.Fatalf("report = %+v", r)
+	}
+	if r.WindowAUC != 1.0 {
+		t.Fatalf("window auc = %v, want 1", r.WindowAUC)
+	}
+	if math.Abs(r.AUC-0.5) > 1e-12 {
+		t.Fatalf("overall auc = %v, want 0.5", r.AUC)
+	}
+	want := -(2*math.Log(0.1) + 2*math.Log(0.2) + 2*math.Log(0.9) + 2*math.Log(0.8)) / 8
+	if math.Abs(r.LogLoss-want) > 1e-12 {
+		t.Fatalf("logloss = %v, want %v", r.LogLoss, want)
+	}
+}
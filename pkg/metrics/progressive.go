@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// progressiveAUCBins 全量AUC使用的直方图桶数
+const progressiveAUCBins = 10000
+
+// ProgressiveValidator 渐进式验证（progressive validation）
+// 在线训练时每个样本先预测再更新，更新前的预测即为该样本的验证结果
+// 累计全部样本的logloss和直方图AUC，并保留最近window个样本计算窗口AUC，并发安全
+type ProgressiveValidator struct {
+	mu        sync.Mutex
+	window    []scoredLabel
+	pos       int
+	full      bool
+	count     int64
+	weightSum float64
+	logLoss   float64
+	auc       *HistogramAUC
+}
+
+// ProgressiveReport 渐进式验证结果
+type ProgressiveReport struct {
+	Count      int64   `json:"count"`
+	Weight     float64 `json:"weight"`
+	LogLoss    float64 `json:"logloss"` // 非二分类目标为训练目标的平均损失
+	AUC        float64 `json:"auc"`
+	WindowSize int     `json:"window_size"`
+	WindowAUC  float64 `json:"window_auc"`
+}
+
+// NewProgressiveValidator 创建渐进式验证器，window为窗口AUC的样本数
+func NewProgressiveValidator(window int) *ProgressiveValidator {
+	return &ProgressiveValidator{
+		window: make([]scoredLabel, window),
+		auc:    NewHistogramAUC(progressiveAUCBins),
+	}
+}
+
+// AddBatch 添加一批样本，probs为更新前的预测概率，labels为0或1，weights为样本权重（nil表示全部为1）
+func (v *ProgressiveValidator) AddBatch(probs, labels, weights []float64) {
+	weight := func(i int) float64 {
+		if weights == nil {
+			return 1.0
+		}
+		return weights[i]
+	}
+	logLoss, weightSum := 0.0, 0.0
+	for i, p := range probs {
+		q := math.Min(math.Max(p, logLossEps), 1.0-logLossEps)
+		logLoss -= weight(i) * (labels[i]*math.Log(q) + (1.0-labels[i])*math.Log(1.0-q))
+		weightSum += weight(i)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.count += int64(len(probs))
+	v.weightSum += weightSum
+	v.logLoss += logLoss
+	for i, p := range probs {
+		v.auc.Add(p, labels[i], weight(i))
+		if len(v.window) == 0 {
+			continue
+		}
+		v.window[v.pos] = scoredLabel{score: p, label: labels[i], weight: weight(i)}
+		v.pos++
+		if v.pos == len(v.window) {
+			v.pos = 0
+			v.full = true
+		}
+	}
+}
+
+// AddLoss 添加一批样本的加权损失之和，用于非二分类的训练目标（不计算AUC）
+func (v *ProgressiveValidator) AddLoss(count int64, lossSum, weightSum float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.count += count
+	v.weightSum += weightSum
+	v.logLoss += lossSum
+}
+
+// Report 计算当前的渐进式验证结果
+func (v *ProgressiveValidator) Report() *ProgressiveReport {
+	v.mu.Lock()
+	items := v.window[:v.pos]
+	if v.full {
+		items = v.window
+	}
+	r := &ProgressiveReport{
+		Count:      v.count,
+		Weight:     v.weightSum,
+		AUC:        v.auc.Value(),
+		WindowSize: len(items),
+	}
+	if v.weightSum > 0 {
+		r.LogLoss = v.logLoss / v.weightSum
+	}
+	items = append([]scoredLabel(nil), items...)
+	v.mu.Unlock()
+
+	r.WindowAUC = exactAUC(items)
+	return r
+}
+
+// String 单行文本格式
+func (r *ProgressiveReport) String() string {
+	return fmt.Sprintf("progressive logloss: %.6f, window auc(%d): %.6f", r.LogLoss, r.WindowSize, r.WindowAUC)
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// countMinDepth count-min sketch的行数
+const countMinDepth = 4
+
+// Admission 新特征的准入策略
+// 特征尚无参数时每出现一次调用一次Admit，返回true时才为其创建参数；
+// 未准入的特征在预测和梯度更新中都视为不存在
+type Admission interface {
+	// Admit id为特征名的哈希（特征哈希模式下为键）
+	Admit(id uint64) bool
+	// Rejected 被拒绝的特征出现次数
+	Rejected() int64
+}
+
+// CountMinAdmission 按出现次数准入：用count-min sketch估计特征出现次数，达到minCount时准入
+// 估计值只会偏大，因此不会推迟准入，但哈希冲突可能让少数低频特征提前准入
+type CountMinAdmission struct {
+	minCount uint32
+	width    uint64
+	counters []uint32
+	rejected int64
+}
+
+// NewCountMinAdmission 创建按出现次数准入的策略，width为每行的计数器个数
+func NewCountMinAdmission(minCount, width int) *CountMinAdmission {
+	return &CountMinAdmission{
+		minCount: uint32(minCount),
+		width:    uint64(width),
+		counters: make([]uint32, countMinDepth*width),
+	}
+}
+
+// Admit 计数加一并判断估计的出现次数是否达到阈值
+func (a *CountMinAdmission) Admit(id uint64) bool {
+	est := ^uint32(0)
+	h := id
+	for d := uint64(0); d < countMinDepth; d++ {
+		// 每行使用不同的混合结果选取计数器
+		h = h*0x9E3779B97F4A7C15 + d
+		h ^= h >> 29
+		c := atomic.AddUint32(&a.counters[d*a.width+h%a.width], 1)
+		if c < est {
+			est = c
+		}
+	}
+	if est >= a.minCount {
+		return true
+	}
+	atomic.AddInt64(&a.rejected, 1)
+	return false
+}
+
+// Rejected 被拒绝的特征出现次数
+func (a *CountMinAdmission) Rejected() int64 {
+	return atomic.LoadInt64(&a.rejected)
+}
+
+// PoissonAdmission 按概率准入：特征每次出现以概率p准入，
+// 出现n次的特征被准入的概率为1-(1-p)^n，不需要保存计数
+type PoissonAdmission struct {
+	p        float64
+	mu       sync.Mutex
+	rng      *rand.Rand
+	rejected int64
+}
+
+// NewPoissonAdmission 创建按概率准入的策略，seed为0时按时间初始化随机数
+func NewPoissonAdmission(p float64, seed int64) *PoissonAdmission {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &PoissonAdmission{p: p, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Admit 以概率p准入
+func (a *PoissonAdmission) Admit(id uint64) bool {
+	a.mu.Lock()
+	ok := a.rng.Float64() < a.p
+	a.mu.Unlock()
+	if !ok {
+		atomic.AddInt64(&a.rejected, 1)
+	}
+	return ok
+}
+
+// Rejected 被拒绝的特征出现次数
+func (a *PoissonAdmission) Rejected() int64 {
+	return atomic.LoadInt64(&a.rejected)
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCountMinAdmission(t *testing.T) {
+	a := NewCountMinAdmission(3, 1024)
+	for i := 1; i <= 3; i++ {
+		if got := a.Admit(42); got != (i == 3) {
+			t.Fatalf("occurrence %d: admit = %v", i, got)
+		}
+	}
+	if a.Rejected() != 2 {
+		t.Fatalf("rejected = %d, want 2", a.Rejected())
+	}
+}
+
+func TestAdmissionSkipsRareFeatures(t *testing.T) {
+	rand.Seed(1)
+	r := rand.New(rand.NewSource(1))
+	lines := genFMLines(1000, r)
+	for i := range lines {
+		lines[i] += " once" + strconv.Itoa(i) + ":1"
+	}
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.AdmitMinCount = 2
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(lines); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只出现一次的特征没有参数，也不影响其他特征的训练
+	if _, ok := trainer.model.GetModelUnit("once0"); ok {
+		t.Fatal("feature seen once should not be admitted")
+	}
+	if trainer.FeatureNum() != 7 {
+		t.Fatalf("feature num = %d, want 7", trainer.FeatureNum())
+	}
+	if rejected, ok := trainer.AdmissionRejected(); !ok || rejected < 1000 {
+		t.Fatalf("rejected = %d, %v", rejected, ok)
+	}
+	pos, _ := trainer.model.GetModelUnit("pos")
+	if pos == nil || pos.Wi <= 0 {
+		t.Fatal("feature pos should be trained")
+	}
+
+	opt.AdmitMinCount = 0
+	opt.AdmitProb = 0.5
+	if _, ok := opt.NewAdmission().(*PoissonAdmission); !ok {
+		t.Fatal("admit_prob should select poisson admission")
+	}
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// bucketArray 限定哈希桶数时的特征存储：按桶号直接索引的预分配单元数组，取代分片map
+// 桶的占用情况记录在位图中；单元的向量通过bind指向按桶连续排列的预分配存储，
+// 不再为每个特征单独分配单元结构和向量，也没有map的键和指针开销。
+// 数组和向量存储一次性按桶数分配，只有被写入的内存页才实际占用物理内存
+type bucketArray[U any] struct {
+	units  []U
+	used   []uint32  // 占用位图，原子读写
+	count  int64     // 占用的桶数
+	vecs   []float64 // 各桶的向量存储，每个桶stride个
+	stride int
+	hooks  bucketHooks[U]
+}
+
+// bucketHooks 桶数组模式下单元的操作，由模型提供
+type bucketHooks[U any] struct {
+	bind func(unit *U, vecs []float64) // 把单元的向量指向该桶的向量存储
+	init func(key uint64, unit *U)     // 初始化新特征的单元，向量已指向零值存储
+	copy func(dst *U, src *U)          // 把src的参数复制到已bind的dst
+}
+
+func newBucketArray[U any](buckets uint64, stride int, hooks bucketHooks[U]) *bucketArray[U] {
+	return &bucketArray[U]{
+		units:  make([]U, buckets),
+		used:   make([]uint32, (buckets+31)/32),
+		vecs:   make([]float64, buckets*uint64(stride)),
+		stride: stride,
+		hooks:  hooks,
+	}
+}
+
+// bytes 预分配的内存字节数：单元数组、向量存储和占用位图
+func (a *bucketArray[U]) bytes() int64 {
+	var zero U
+	return int64(len(a.units))*int64(unsafe.Sizeof(zero)) + int64(len(a.vecs))*8 + int64(len(a.used))*4
+}
+
+// has 桶是否已被占用
+func (a *bucketArray[U]) has(key uint64) bool {
+	return atomic.LoadUint32(&a.used[key>>5])&(1<<(key&31)) != 0
+}
+
+// markUsed 标记桶已占用，调用方需持有该桶所在分片的锁；相邻的桶属于不同分片，位图的同一个字可能被并发修改
+func (a *bucketArray[U]) markUsed(key uint64) {
+	word, bit := &a.used[key>>5], uint32(1)<<(key&31)
+	for {
+		old := atomic.LoadUint32(word)
+		if atomic.CompareAndSwapUint32(word, old, old|bit) {
+			break
+		}
+	}
+	atomic.AddInt64(&a.count, 1)
+}
+
+// slot 绑定了向量存储的桶单元
+func (a *bucketArray[U]) slot(key uint64) *U {
+	unit := &a.units[key]
+	a.hooks.bind(unit, a.vecs[key*uint64(a.stride):(key+1)*uint64(a.stride)])
+	return unit
+}
+
+// create 在桶中创建新单元，调用方需持有该桶所在分片的锁
+func (a *bucketArray[U]) create(key uint64) *U {
+	unit := a.slot(key)
+	a.hooks.init(key, unit)
+	a.markUsed(key)
+	return unit
+}
+
+// set 加载模型时把单元的参数复制到桶中
+func (a *bucketArray[U]) set(key uint64, unit *U) {
+	dst := a.slot(key)
+	a.hooks.copy(dst, unit)
+	if !a.has(key) {
+		a.markUsed(key)
+	}
+}
+
+// forEach 按桶号从小到大遍历占用的桶
+func (a *bucketArray[U]) forEach(fn func(key uint64, unit *U) error) error {
+	for w, bits := range a.used {
+		for b := uint64(0); bits != 0; b++ {
+			if bits&1 != 0 {
+				key := uint64(w)*32 + b
+				if err := fn(key, &a.units[key]); err != nil {
+					return err
+				}
+			}
+			bits >>= 1
+		}
+	}
+	return nil
+}
+
+// clone 深拷贝，复制的数组使用新的向量存储
+func (a *bucketArray[U]) clone() *bucketArray[U] {
+	c := newBucketArray[U](uint64(len(a.units)), a.stride, a.hooks)
+	a.forEach(func(key uint64, unit *U) error {
+		c.set(key, unit)
+		return nil
+	})
+	return c
+}
+
+// remove 清空桶，向量存储置零以便之后重新创建；调用时不能有并发的训练
+func (a *bucketArray[U]) remove(key uint64) {
+	var zero U
+	a.units[key] = zero
+	vecs := a.vecs[key*uint64(a.stride) : (key+1)*uint64(a.stride)]
+	for i := range vecs {
+		vecs[i] = 0
+	}
+	a.used[key>>5] &^= 1 << (key & 31)
+	a.count--
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutputModelAtomic 先写入同目录下的临时文件再重命名，写入过程中崩溃不会破坏已有的模型文件
+// 临时文件写入、刷盘和关闭全部成功后才重命名，重命名后再对目录刷盘，
+// 磁盘写满等错误不会用不完整的文件覆盖上一个检查点
+func (m *FTRLModel) OutputModelAtomic(modelPath, modelFormat string) error {
+	tmpPath := modelPath + ".tmp"
+	if err := m.outputModel(tmpPath, modelFormat, true); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, modelPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(filepath.Dir(modelPath))
+}
+
+// syncDir 把目录项的修改刷到磁盘，使重命名在掉电后仍然有效
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("sync directory %s: %v", dir, err)
+	}
+	return nil
+}
+
+// Snapshot 复制当前模型（含优化器步数），调用时不能有并发的训练更新
+func (t *FTRLTrainer) Snapshot() *FTRLModel {
+	if so, ok := t.optimizer.(stepOptimizer); ok {
+		t.model.Meta.OptimizerStep = so.Steps()
+	}
+	return t.model.Clone()
+}
+
+// ResumeLines 初始模型为检查点时，返回检查点记录的已训练输入行数
+func (t *FTRLTrainer) ResumeLines() int64 {
+	return t.resumeLines
+}
+
+// Checkpointer 在后台写入模型检查点
+// 快照在训练暂停时复制，写文件与训练并行；同一时刻最多一个写入任务，新的检查点会等待上一个写完
+type Checkpointer struct {
+	path   string
+	format string
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	err    error
+	count  int
+}
+
+// NewCheckpointer 创建检查点写入器
+func NewCheckpointer(path, format string) *Checkpointer {
+	return &Checkpointer{path: path, format: format}
+}
+
+// Save 在后台写入快照，inputLines为快照对应的已训练输入行数
+func (c *Checkpointer) Save(snapshot *FTRLModel, inputLines int64) {
+	c.wg.Wait()
+	snapshot.Meta.InputLines = inputLines
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		start := time.Now()
+		err := snapshot.OutputModelAtomic(c.path, c.format)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err != nil {
+			c.err = err
+			fmt.Printf("Warning: write checkpoint %s error: %v\n", c.path, err)
+			return
+		}
+		c.count++
+		fmt.Printf("checkpoint saved to %s at %d lines (%.1fs), resume with -im %s -imf %s -skip %d\n",
+			c.path, inputLines, time.Since(start).Seconds(), c.path, c.format, inputLines)
+	}()
+}
+
+// Wait 等待正在写入的检查点完成，返回最近一次写入错误
+func (c *Checkpointer) Wait() error {
+	c.wg.Wait()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
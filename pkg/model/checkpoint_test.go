@@ -0,0 +1,116 @@
+package model
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	rand.Seed(1)
+	r := rand.New(rand.NewSource(1))
+	dir := t.TempDir()
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(genFMLines(1000, r)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"txt", "bin"} {
+		path := filepath.Join(dir, "ckpt."+format)
+		c := NewCheckpointer(path, format)
+		c.Save(trainer.Snapshot(), 1000)
+		if err := c.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Fatalf("%s: temp file should be renamed", format)
+		}
+
+		resumeOpt := NewTrainerOption()
+		resumeOpt.FactorNum = 2
+		resumed := NewFTRLTrainer(resumeOpt)
+		if err := resumed.LoadModel(path, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if resumed.ResumeLines() != 1000 {
+			t.Fatalf("%s: resume lines = %d, want 1000", format, resumed.ResumeLines())
+		}
+		if resumed.model.FeatureNum() != trainer.model.FeatureNum() {
+			t.Fatalf("%s: feature num = %d, want %d", format, resumed.model.FeatureNum(), trainer.model.FeatureNum())
+		}
+
+		// 续训后输出的模型不再带有检查点行数
+		out := filepath.Join(dir, "out."+format)
+		if err := resumed.OutputModel(out, format); err != nil {
+			t.Fatal(err)
+		}
+		m := NewFTRLModel(2, 0, 0.1)
+		if err := m.LoadModel(out, format); err != nil {
+			t.Fatal(err)
+		}
+		if m.Meta.InputLines != 0 || !m.Meta.IsDefault() {
+			t.Fatalf("%s: output meta = %+v", format, m.Meta)
+		}
+	}
+
+	// 快照与训练中的模型互不影响
+	snap := trainer.Snapshot()
+	before := snap.MuBias.Wi
+	if err := trainer.RunTask(genFMLines(100, r)); err != nil {
+		t.Fatal(err)
+	}
+	if snap.MuBias.Wi != before || trainer.model.MuBias.Wi == before {
+		t.Fatal("snapshot should not share units with the trained model")
+	}
+}
+
+func TestCheckpointWriteFailure(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(genFMLines(200, r)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 磁盘写满：刷新和写入的错误都要返回
+	if _, err := os.Stat("/dev/full"); err == nil {
+		for _, format := range []string{"txt", "bin"} {
+			if err := trainer.model.OutputModel("/dev/full", format); err == nil {
+				t.Errorf("%s: expected error writing to a full device", format)
+			}
+		}
+	}
+
+	// 临时文件写入失败时不覆盖上一个检查点
+	for _, format := range []string{"txt", "bin"} {
+		path := filepath.Join(t.TempDir(), "ckpt."+format)
+		c := NewCheckpointer(path, format)
+		c.Save(trainer.Snapshot(), 100)
+		if err := c.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		before, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(path+".tmp", 0755); err != nil {
+			t.Fatal(err)
+		}
+		c.Save(trainer.Snapshot(), 200)
+		if err := c.Wait(); err == nil {
+			t.Fatalf("%s: expected checkpoint error", format)
+		}
+		after, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(before) != string(after) {
+			t.Fatalf("%s: previous checkpoint was overwritten", format)
+		}
+	}
+}
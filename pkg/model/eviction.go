@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// EvictionStats 一次淘汰扫描的结果
+type EvictionStats struct {
+	Scanned   int   // 扫描的特征数
+	Stale     int   // 因长时间未出现淘汰的特征数
+	LowWeight int   // 因权重持续过小淘汰的特征数
+	Bytes     int64 // 估计回收的内存（字节）
+}
+
+// Evicted 淘汰的特征总数
+func (s EvictionStats) Evicted() int {
+	return s.Stale + s.LowWeight
+}
+
+// String 日志格式
+func (s EvictionStats) String() string {
+	return fmt.Sprintf("evicted %d of %d features (stale: %d, low weight: %d), reclaimed about %.2f MB",
+		s.Evicted(), s.Scanned, s.Stale, s.LowWeight, float64(s.Bytes)/(1<<20))
+}
+
+// evictionEnabled 是否开启特征淘汰
+func (opt *TrainerOption) evictionEnabled() bool {
+	return opt.EvictStale > 0 || opt.lowWeightEviction()
+}
+
+// lowWeightEviction 是否按权重淘汰
+func (opt *TrainerOption) lowWeightEviction() bool {
+	return opt.EvictMinW > 0 || opt.EvictMinV > 0
+}
+
+// touch 记录特征最近一次出现时已训练的样本数
+func (u *FTRLModelUnit) touch(seen int64) {
+	if atomic.LoadInt64(&u.lastSeen) != seen {
+		atomic.StoreInt64(&u.lastSeen, seen)
+	}
+}
+
+// stamp 开启淘汰时返回当前已训练的样本数，用于记录特征最近一次出现，否则返回-1
+func (t *FTRLTrainer) stamp() int64 {
+	if !t.opt.evictionEnabled() {
+		return -1
+	}
+	return atomic.LoadInt64(&t.seen)
+}
+
+// Evict 扫描模型并淘汰特征，必须在没有并发训练时调用（如PCFrame的暂停点）
+//   - 已训练样本数与特征最近一次出现之差达到EvictStale时淘汰
+//   - |w|<=EvictMinW且||v||<=EvictMinV，并且上一次扫描时也是如此时淘汰
+//
+// 被淘汰的特征再次出现时重新初始化
+func (t *FTRLTrainer) Evict() EvictionStats {
+	var stats EvictionStats
+	seen := atomic.LoadInt64(&t.seen)
+	lowWeight := t.opt.lowWeightEviction()
+	removed, keyBytes := t.model.store.removeIf(func(u *FTRLModelUnit) bool {
+		stats.Scanned++
+		if t.opt.EvictStale > 0 && seen-u.lastSeen >= t.opt.EvictStale {
+			stats.Stale++
+			return true
+		}
+		if !lowWeight {
+			return false
+		}
+		low := math.Abs(u.Wi) <= t.opt.EvictMinW && vecNorm(u.Vi) <= t.opt.EvictMinV
+		if low && u.lowWeight {
+			stats.LowWeight++
+			return true
+		}
+		u.lowWeight = low
+		return false
+	})
+	// 桶数组模式下桶是预分配的，淘汰不回收内存
+	if t.model.store.buckets == nil {
+		stats.Bytes = int64(removed)*t.model.unitBytes() + keyBytes
+	}
+	return stats
+}
+
+// unitBytes 估计每个特征单元除键以外占用的内存：单元结构、隐向量及优化器状态、map中的键头和指针
+func (m *FTRLModel) unitBytes() int64 {
+	const mapEntry = int64(unsafe.Sizeof("")) + int64(unsafe.Sizeof(uintptr(0)))
+	return int64(unsafe.Sizeof(FTRLModelUnit{})) + 3*8*int64(m.VecLen()) + mapEntry
+}
+
+func vecNorm(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
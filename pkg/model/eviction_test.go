@@ -0,0 +1,59 @@
+package model
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEvictStale(t *testing.T) {
+	rand.Seed(1)
+	r := rand.New(rand.NewSource(1))
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.EvictStale = 500
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask([]string{"1 old:1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := trainer.RunTask(genFMLines(600, r)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := trainer.Evict()
+	if stats.Stale != 1 || stats.Scanned != 8 || stats.Bytes <= 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if _, ok := trainer.model.GetModelUnit("old"); ok {
+		t.Fatal("stale feature should be evicted")
+	}
+	if trainer.FeatureNum() != 7 {
+		t.Fatalf("feature num = %d, want 7", trainer.FeatureNum())
+	}
+}
+
+func TestEvictLowWeight(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.EvictMinW = 0.01
+	opt.EvictMinV = 0.01
+	trainer := NewFTRLTrainer(opt)
+	small := trainer.model.GetOrInitModelUnit("small")
+	small.Vi[0], small.Vi[1] = 0.001, 0
+	big := trainer.model.GetOrInitModelUnit("big")
+	big.Wi = 1
+
+	// 第一次扫描只做标记，连续两次低于阈值才淘汰
+	if stats := trainer.Evict(); stats.Evicted() != 0 {
+		t.Fatalf("first sweep evicted %d", stats.Evicted())
+	}
+	if stats := trainer.Evict(); stats.LowWeight != 1 {
+		t.Fatalf("second sweep stats = %+v", stats)
+	}
+	if _, ok := trainer.model.GetModelUnit("small"); ok {
+		t.Fatal("low weight feature should be evicted")
+	}
+	if _, ok := trainer.model.GetModelUnit("big"); !ok {
+		t.Fatal("feature big should be kept")
+	}
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// Explanation 一个样本预测得分的分解
+// Bias + Σ特征的FirstOrder + Σ特征的Pairwise 等于link函数之前的原始得分Raw
+type Explanation struct {
+	Score           float64               `json:"score"`    // 预测值，经过link函数和负样本采样率校准，与fm_predict的输出一致
+	Raw             float64               `json:"raw"`      // link函数之前的得分
+	Bias            float64               `json:"bias"`     // 全局偏置
+	Pairwise        float64               `json:"pairwise"` // 二阶交互项之和
+	Features        []FeatureContribution `json:"features"` // 在模型中的特征，按贡献的绝对值从大到小
+	TopInteractions []Interaction         `json:"top_interactions"`
+	Missing         []string              `json:"missing"` // 不在模型中的特征，不参与打分
+}
+
+// FeatureContribution 一个特征对原始得分的贡献
+type FeatureContribution struct {
+	Feature    string  `json:"feature"`
+	Value      float64 `json:"value"`
+	FirstOrder float64 `json:"first_order"` // w_i·x_i
+	Pairwise   float64 `json:"pairwise"`    // 该特征所在交互项之和的一半，各交互项平分给两个特征
+	Total      float64 `json:"total"`
+}
+
+// Interaction 两个特征的交互项 <v_i,v_j>·x_i·x_j
+type Interaction struct {
+	A     string  `json:"a"`
+	B     string  `json:"b"`
+	Value float64 `json:"value"`
+}
+
+// Explain 分解一个样本的预测得分，topN为按绝对值输出的交互项个数，0表示不输出
+// 支持FM和FFM模型，交互项逐对计算，复杂度为特征数的平方
+func (m *PredictModel) Explain(x []sample.FeatureValue, topN int) (*Explanation, error) {
+	if m.Meta.ClassNum > 0 {
+		return nil, fmt.Errorf("explain does not support multiclass models")
+	}
+	e := &Explanation{
+		Bias:            m.MuBias.Wi,
+		Features:        []FeatureContribution{},
+		TopInteractions: []Interaction{},
+		Missing:         []string{},
+	}
+
+	units := make([]*PredictModelUnit, 0, len(x))
+	xs := make([]sample.FeatureValue, 0, len(x))
+	for i := range x {
+		unit, ok := m.store.get(x[i].Feature)
+		if !ok {
+			e.Missing = append(e.Missing, x[i].Feature)
+			continue
+		}
+		units = append(units, unit)
+		xs = append(xs, x[i])
+		e.Features = append(e.Features, FeatureContribution{
+			Feature:    x[i].Feature,
+			Value:      x[i].Value,
+			FirstOrder: unit.Wi * x[i].Value,
+		})
+	}
+
+	for i := 0; i < len(units); i++ {
+		for j := i + 1; j < len(units); j++ {
+			term := m.pairDot(units[i], units[j], xs[i].Field, xs[j].Field) * xs[i].Value * xs[j].Value
+			e.Pairwise += term
+			e.Features[i].Pairwise += 0.5 * term
+			e.Features[j].Pairwise += 0.5 * term
+			if topN > 0 {
+				e.TopInteractions = addTopInteraction(e.TopInteractions, Interaction{xs[i].Feature, xs[j].Feature, term}, topN)
+			}
+		}
+	}
+
+	e.Raw = e.Bias + e.Pairwise
+	for i := range e.Features {
+		f := &e.Features[i]
+		f.Total = f.FirstOrder + f.Pairwise
+		e.Raw += f.FirstOrder
+	}
+	sort.SliceStable(e.Features, func(i, j int) bool {
+		return math.Abs(e.Features[i].Total) > math.Abs(e.Features[j].Total)
+	})
+	e.Score = m.Calibrate(m.loss().Link(e.Raw))
+	return e, nil
+}
+
+// pairDot 两个特征隐向量的内积，FFM使用对方field对应的隐向量
+func (m *PredictModel) pairDot(a, b *PredictModelUnit, fieldA, fieldB int) float64 {
+	k := m.FactorNum
+	va, vb := a.Vi[:k], b.Vi[:k]
+	if m.Meta.ModelType == ModelTypeFFM {
+		va = a.Vi[fieldB*k : (fieldB+1)*k]
+		vb = b.Vi[fieldA*k : (fieldA+1)*k]
+	}
+	dot := 0.0
+	for f := 0; f < k; f++ {
+		dot += va[f] * vb[f]
+	}
+	return dot
+}
+
+// addTopInteraction 把交互项插入按绝对值从大到小排列、最多n个的列表
+func addTopInteraction(top []Interaction, it Interaction, n int) []Interaction {
+	abs := math.Abs(it.Value)
+	if len(top) == n && abs <= math.Abs(top[n-1].Value) {
+		return top
+	}
+	pos := sort.Search(len(top), func(i int) bool { return math.Abs(top[i].Value) < abs })
+	if len(top) < n {
+		top = append(top, Interaction{})
+	}
+	copy(top[pos+1:], top[pos:len(top)-1])
+	top[pos] = it
+	return top
+}
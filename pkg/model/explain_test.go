@@ -0,0 +1,116 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// checkExplanation 检查得分分解的各部分之和与模型打分一致
+func checkExplanation(t *testing.T, e *Explanation, want float64) {
+	t.Helper()
+	if math.Abs(e.Score-want) > 1e-12 {
+		t.Fatalf("score = %v, want %v", e.Score, want)
+	}
+	raw, pairwise := e.Bias, 0.0
+	for i, f := range e.Features {
+		raw += f.FirstOrder + f.Pairwise
+		pairwise += f.Pairwise
+		if i > 0 && math.Abs(f.Total) > math.Abs(e.Features[i-1].Total) {
+			t.Fatalf("features not sorted by contribution: %+v", e.Features)
+		}
+	}
+	if math.Abs(raw-e.Raw) > 1e-12 || math.Abs(pairwise-e.Pairwise) > 1e-12 {
+		t.Fatalf("contributions do not add up: raw %v vs %v, pairwise %v vs %v", raw, e.Raw, pairwise, e.Pairwise)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 4
+	opt.Seed = 1
+	trainer := NewFTRLTrainer(opt)
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines, "1 a:1 b:1 d:0.5", "0 a:1 c:1", "0 b:0.5 c:1 d:1")
+	}
+	if err := trainer.RunTask(lines); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "model.txt")
+	if err := trainer.OutputModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPredictModel(4)
+	if err := pm.LoadModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sample.ParseSample("1 a:1 unknown:2 b:1 c:0.3 d:0.5", sample.LabelBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := make([]struct {
+		Feature string
+		Value   float64
+	}, len(s.X))
+	for i, fv := range s.X {
+		x[i].Feature, x[i].Value = fv.Feature, fv.Value
+	}
+	e, err := pm.Explain(s.X, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExplanation(t, e, pm.GetScore(x, pm.MuBias.Wi))
+	if len(e.Features) != 4 || len(e.Missing) != 1 || e.Missing[0] != "unknown" {
+		t.Fatalf("features %+v, missing %v", e.Features, e.Missing)
+	}
+	// 4个特征共6个交互项，只保留绝对值最大的2个
+	if len(e.TopInteractions) != 2 || math.Abs(e.TopInteractions[0].Value) < math.Abs(e.TopInteractions[1].Value) {
+		t.Fatalf("top interactions = %+v", e.TopInteractions)
+	}
+	all, _ := pm.Explain(s.X, 10)
+	if len(all.TopInteractions) != 6 || all.TopInteractions[0] != e.TopInteractions[0] || all.TopInteractions[1] != e.TopInteractions[1] {
+		t.Fatalf("top interactions %+v, all %+v", e.TopInteractions, all.TopInteractions)
+	}
+
+	pm.Meta.ClassNum = 3
+	if _, err := pm.Explain(s.X, 2); err == nil {
+		t.Fatal("expected error for multiclass models")
+	}
+}
+
+func TestExplainFFM(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	opt := NewTrainerOption()
+	opt.FactorNum = 4
+	opt.ModelType = ModelTypeFFM
+	opt.FieldNum = 3
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(genFFMLines(2000, r)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ffm.txt")
+	if err := trainer.OutputModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPredictModel(4)
+	pm.Meta = opt.ModelMeta()
+	if err := pm.LoadModel(path, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range genFFMLines(20, r) {
+		s, err := sample.ParseFFMSample(line, 3, sample.LabelBinary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := pm.Explain(s.X, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkExplanation(t, e, pm.GetScoreFFM(s.X, pm.MuBias.Wi))
+	}
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"sync"
+)
+
+// fnv-1a 64位参数
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// fingerprintSeed 冲突统计时计算特征指纹使用的种子扰动
+const fingerprintSeed = 0x9E3779B97F4A7C15
+
+// hashStatShardNum 冲突统计的分片数
+const hashStatShardNum = 64
+
+// FeatureHasher 特征哈希：把特征名映射为64位键，buckets>0时再对桶数取模
+// 开启后模型不再保存特征名，内存占用与特征名长度无关
+type FeatureHasher struct {
+	Seed    uint64
+	Buckets uint64
+	stats   *hashStats
+}
+
+// NewFeatureHasher 创建特征哈希
+func NewFeatureHasher(seed, buckets uint64) *FeatureHasher {
+	return &FeatureHasher{Seed: seed, Buckets: buckets}
+}
+
+// seededHash 带种子的fnv-1a，最后做一次splitmix64混合使低位分布均匀
+func seededHash(seed uint64, s string) uint64 {
+	h := uint64(fnvOffset64) ^ (seed * fnvPrime64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	h ^= h >> 30
+	h *= 0xBF58476D1CE4E5B9
+	h ^= h >> 27
+	h *= 0x94D049BB133111EB
+	h ^= h >> 31
+	return h
+}
+
+// Key 计算特征的键
+func (h *FeatureHasher) Key(feature string) uint64 {
+	key := seededHash(h.Seed, feature)
+	if h.Buckets > 0 {
+		key %= h.Buckets
+	}
+	return key
+}
+
+// record 开启冲突统计时记录训练中出现的特征，只在训练创建/查找参数时调用，
+// 验证和预测的只读查找不计入统计
+func (h *FeatureHasher) record(feature string, key uint64) {
+	if h.stats != nil {
+		h.stats.add(key, seededHash(h.Seed^fingerprintSeed, feature))
+	}
+}
+
+// EnableStats 开启冲突统计（需要保存每个不同特征的指纹，仅用于诊断）
+func (h *FeatureHasher) EnableStats() {
+	h.stats = newHashStats()
+}
+
+// HashStats 冲突统计结果
+type HashStats struct {
+	Features   int64 // 不同特征数（按指纹去重）
+	Keys       int64 // 占用的键数
+	Collisions int64 // 与其他特征共享键的特征数，即Features-Keys
+}
+
+// CollisionRate 冲突率
+func (s HashStats) CollisionRate() float64 {
+	if s.Features == 0 {
+		return 0
+	}
+	return float64(s.Collisions) / float64(s.Features)
+}
+
+// Stats 返回冲突统计，未开启时返回false
+func (h *FeatureHasher) Stats() (HashStats, bool) {
+	if h.stats == nil {
+		return HashStats{}, false
+	}
+	return h.stats.result(), true
+}
+
+// hashStats 冲突统计，按指纹分片加锁
+type hashStats struct {
+	shards [hashStatShardNum]struct {
+		mu           sync.Mutex
+		fingerprints map[uint64]struct{}
+	}
+	keysMu sync.Mutex
+	keys   map[uint64]struct{}
+}
+
+func newHashStats() *hashStats {
+	s := &hashStats{keys: make(map[uint64]struct{})}
+	for i := range s.shards {
+		s.shards[i].fingerprints = make(map[uint64]struct{})
+	}
+	return s
+}
+
+func (s *hashStats) add(key, fingerprint uint64) {
+	shard := &s.shards[fingerprint%hashStatShardNum]
+	shard.mu.Lock()
+	_, seen := shard.fingerprints[fingerprint]
+	if !seen {
+		shard.fingerprints[fingerprint] = struct{}{}
+	}
+	shard.mu.Unlock()
+	if seen {
+		return
+	}
+
+	s.keysMu.Lock()
+	s.keys[key] = struct{}{}
+	s.keysMu.Unlock()
+}
+
+func (s *hashStats) result() HashStats {
+	var r HashStats
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		r.Features += int64(len(s.shards[i].fingerprints))
+		s.shards[i].mu.Unlock()
+	}
+	s.keysMu.Lock()
+	r.Keys = int64(len(s.keys))
+	s.keysMu.Unlock()
+	r.Collisions = r.Features - r.Keys
+	return r
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFeatureHashingRoundTrip(t *testing.T) {
+	rand.Seed(1)
+	r := rand.New(rand.NewSource(1))
+	dir := t.TempDir()
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.Hashed = true
+	opt.HashSeed = 7
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(genFMLines(1000, r)); err != nil {
+		t.Fatal(err)
+	}
+
+	x := []struct {
+		Feature string
+		Value   float64
+	}{{"pos", 1}, {"noisea", 1}}
+	scores := make(map[string]float64)
+
+	for _, format := range []string{"txt", "bin"} {
+		path := filepath.Join(dir, "hashed."+format)
+		if err := trainer.OutputModel(path, format); err != nil {
+			t.Fatal(err)
+		}
+
+		// 预测模型根据元信息自动开启特征哈希
+		pm := NewPredictModel(2)
+		if err := pm.LoadModel(path, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !pm.Meta.Hashed || pm.Meta.HashSeed != 7 {
+			t.Fatalf("%s: meta = %+v", format, pm.Meta)
+		}
+		if pm.FeatureNum() != trainer.model.FeatureNum() {
+			t.Fatalf("%s: feature num = %d, want %d", format, pm.FeatureNum(), trainer.model.FeatureNum())
+		}
+		if _, ok := pm.GetUnit("pos"); !ok {
+			t.Fatalf("%s: feature pos not found", format)
+		}
+		scores[format] = pm.GetScore(x, 1.0)
+
+		// 哈希配置不一致时拒绝加载
+		plain := NewTrainerOption()
+		plain.FactorNum = 2
+		if err := NewFTRLTrainer(plain).LoadModel(path, format); err == nil {
+			t.Fatalf("%s: loading a hashed model without hashing should fail", format)
+		}
+		same := *opt
+		if err := NewFTRLTrainer(&same).LoadModel(path, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+	}
+	if math.Abs(scores["txt"]-scores["bin"]) > 1e-4 {
+		t.Fatalf("txt score %v != bin score %v", scores["txt"], scores["bin"])
+	}
+}
+
+func TestFeatureHashStats(t *testing.T) {
+	h := NewFeatureHasher(1, 4)
+	h.EnableStats()
+	for i := 0; i < 100; i++ {
+		feature := "f" + strconv.Itoa(i%20)
+		key := h.Key(feature)
+		h.record(feature, key)
+		if key >= 4 {
+			t.Fatalf("key %d out of buckets", key)
+		}
+	}
+	stats, ok := h.Stats()
+	if !ok {
+		t.Fatal("stats should be enabled")
+	}
+	if stats.Features != 20 || stats.Keys > 4 || stats.Collisions != stats.Features-stats.Keys {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// 只读查找不计入统计，只有训练时创建/查找参数才记录
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.Hashed = true
+	opt.HashStats = true
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask([]string{"1 a:1 b:1", "0 c:1"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		trainer.model.GetModelUnit("unseen" + strconv.Itoa(i))
+	}
+	if stats, _ := trainer.HashStats(); stats.Features != 3 {
+		t.Fatalf("stats after read-only lookups = %+v, want 3 features", stats)
+	}
+
+	if _, ok := NewFeatureHasher(1, 0).Stats(); ok {
+		t.Fatal("stats should be disabled by default")
+	}
+	if NewFeatureHasher(1, 0).Key("a") == NewFeatureHasher(2, 0).Key("a") {
+		t.Fatal("different seeds should give different keys")
+	}
+}
+
+func TestHashBucketStore(t *testing.T) {
+	lines := genFMLines(2000, rand.New(rand.NewSource(1)))
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.Hashed = true
+	opt.HashBuckets = 64
+	opt.Seed = 3
+	newTrainer := func() *FTRLTrainer {
+		trainer := NewFTRLTrainer(opt)
+		if trainer.model.store.buckets == nil {
+			t.Fatal("bucket array should be used with hash buckets")
+		}
+		return trainer
+	}
+
+	// 桶数组与分片map训练出的模型逐位相同
+	a := newTrainer()
+	b := newTrainer()
+	b.model.store = newUnitStore[FTRLModelUnit](b.model.Hasher(), defaultShardNum)
+	for _, trainer := range []*FTRLTrainer{a, b} {
+		if err := trainer.RunTask(lines); err != nil {
+			t.Fatal(err)
+		}
+	}
+	units := make(map[string]*FTRLModelUnit)
+	b.model.store.forEach(func(name string, unit *FTRLModelUnit) error {
+		units[name] = unit
+		return nil
+	})
+	if a.model.FeatureNum() != len(units) {
+		t.Fatalf("feature num = %d, want %d", a.model.FeatureNum(), len(units))
+	}
+	last := int64(-1)
+	a.model.store.forEach(func(name string, ua *FTRLModelUnit) error {
+		key, _ := strconv.ParseInt(name, 10, 64)
+		if key <= last {
+			t.Fatalf("keys not in order: %d after %d", key, last)
+		}
+		last = key
+		ub := units[name]
+		if ub == nil || ua.Wi != ub.Wi || ua.WNi != ub.WNi || ua.WZi != ub.WZi {
+			t.Fatalf("key %s: w differs", name)
+		}
+		for f := range ua.Vi {
+			if ua.Vi[f] != ub.Vi[f] || ua.VNi[f] != ub.VNi[f] || ua.VZi[f] != ub.VZi[f] {
+				t.Fatalf("key %s: v[%d] differs", name, f)
+			}
+		}
+		return nil
+	})
+
+	// 加载到桶数组中，快照不与原模型共享参数
+	for _, format := range []string{"txt", "bin"} {
+		path := filepath.Join(t.TempDir(), "buckets."+format)
+		if err := a.OutputModel(path, format); err != nil {
+			t.Fatal(err)
+		}
+		loaded := newTrainer()
+		if err := loaded.LoadModel(path, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if loaded.model.store.buckets == nil || loaded.model.FeatureNum() != a.model.FeatureNum() {
+			t.Fatalf("%s: feature num = %d, want %d", format, loaded.model.FeatureNum(), a.model.FeatureNum())
+		}
+	}
+	pos, _ := a.model.GetModelUnit("pos")
+	snapshot := a.model.Clone()
+	want := pos.Vi[0]
+	pos.Vi[0]++
+	if u, _ := snapshot.GetModelUnit("pos"); u.Vi[0] != want {
+		t.Fatal("snapshot shares parameters with the model")
+	}
+
+	// 淘汰后重新出现的特征从零状态开始
+	removed, _ := a.model.store.removeIf(func(*FTRLModelUnit) bool { return true })
+	if removed != len(units) || a.model.FeatureNum() != 0 {
+		t.Fatalf("removed %d of %d, %d left", removed, len(units), a.model.FeatureNum())
+	}
+	if u := a.model.GetOrInitModelUnit("pos"); u.WNi != 0 || u.VNi[0] != 0 || u.VZi[1] != 0 || a.model.FeatureNum() != 1 {
+		t.Fatalf("recreated unit = %+v", *u)
+	}
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// trainFFM 训练一个FFM样本，返回更新前的线性预测值（未经link函数），label为原始标签，weight为样本权重
+// 每个特征单元的Vi按field分块: Vi[f*k:(f+1)*k]为该特征与field f交互时使用的隐向量，
+// VNi、VZi同样分块，即每个field有独立的优化器状态
+func (t *FTRLTrainer) trainFFM(label, weight float64, x []sample.FeatureValue) float64 {
+	thetaBias := t.model.GetOrInitModelUnitBias()
+	x, theta, feaLocks := t.getUnitsAndLocks(x)
+	xLen := len(x)
+	t.tick()
+
+	// 更新w
+	t.updateW(theta, thetaBias, feaLocks)
+
+	// 更新v，只需更新样本中出现的field对应的分块
+	k := t.model.FactorNum
+	for i := 0; i < xLen; i++ {
+		mu := theta[i]
+		feaLocks[i].Lock()
+		for j := 0; j < xLen; j++ {
+			if j == i {
+				continue
+			}
+			offset := x[j].Field * k
+			for f := offset; f < offset+k; f++ {
+				t.updateVi(mu, f)
+			}
+		}
+		feaLocks[i].Unlock()
+	}
+
+	// 预测
+	p := predictFFM(x, thetaBias.Wi, theta, k)
+
+	// 计算梯度系数
+	mult := weight * t.loss.Gradient(p, label)
+
+	// 更新w_n, w_z
+	t.updateWGradients(theta, thetaBias, feaLocks, x, mult)
+
+	// 更新v_n, v_z
+	// v_{i,fj}的梯度为 mult * Σ_{j: field_j=fj} v_{j,fi} * xi * xj，同一field的梯度先累加再更新
+	fieldNum := t.model.Meta.FieldNum
+	grad := make([]float64, fieldNum*k)
+	touched := make([]bool, fieldNum)
+	for i := 0; i < xLen; i++ {
+		mu := theta[i]
+		xi := x[i].Value
+		fi := x[i].Field
+
+		for f := range grad {
+			grad[f] = 0.0
+		}
+		for f := range touched {
+			touched[f] = false
+		}
+
+		for j := 0; j < xLen; j++ {
+			if j == i {
+				continue
+			}
+			fj := x[j].Field
+			vj := theta[j].Vi[fi*k : (fi+1)*k]
+			g := grad[fj*k : (fj+1)*k]
+			xixj := mult * xi * x[j].Value
+			for f := 0; f < k; f++ {
+				g[f] += vj[f] * xixj
+			}
+			touched[fj] = true
+		}
+
+		feaLocks[i].Lock()
+		for fj := 0; fj < fieldNum; fj++ {
+			if !touched[fj] {
+				continue
+			}
+			for f := fj * k; f < (fj+1)*k; f++ {
+				t.updateViGradient(mu, f, grad[f])
+			}
+		}
+		feaLocks[i].Unlock()
+	}
+
+	return p
+}
+
+// predictFFM FFM预测（不含link函数）
+// score = bias + Σ wi*xi + Σ_{i<j} <v_{i,fj}, v_{j,fi}> * xi * xj
+func predictFFM(x []sample.FeatureValue, bias float64, theta []*FTRLModelUnit, k int) float64 {
+	xLen := len(x)
+	result := bias
+
+	// 一阶项
+	for i := 0; i < xLen; i++ {
+		result += theta[i].Wi * x[i].Value
+	}
+
+	// 二阶交互项
+	for i := 0; i < xLen; i++ {
+		fi := x[i].Field
+		for j := i + 1; j < xLen; j++ {
+			fj := x[j].Field
+			vi := theta[i].Vi[fj*k : (fj+1)*k]
+			vj := theta[j].Vi[fi*k : (fi+1)*k]
+			dot := 0.0
+			for f := 0; f < k; f++ {
+				dot += vi[f] * vj[f]
+			}
+			result += dot * x[i].Value * x[j].Value
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// genFFMLines 生成field交互数据: 当用户和物品同组时为正样本
+func genFFMLines(n int, r *rand.Rand) []string {
+	lines := make([]string, n)
+	for i := 0; i < n; i++ {
+		u, it := r.Intn(12), r.Intn(12)
+		y := 0
+		if u%2 == it%2 {
+			y = 1
+		}
+		lines[i] = fmt.Sprintf("%d 0:u%d:1 1:i%d:1 2:c%d:1", y, u, it, r.Intn(3))
+	}
+	return lines
+}
+
+func TestFFMTrainSaveLoad(t *testing.T) {
+	rand.Seed(1)
+	r := rand.New(rand.NewSource(1))
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 4
+	opt.ModelType = ModelTypeFFM
+	opt.FieldNum = 3
+	opt.WL1, opt.VL1 = 0, 0
+	opt.WL2, opt.VL2 = 0.1, 0.1
+	opt.VAlpha = 0.1
+	trainer := NewFTRLTrainer(opt)
+	for epoch := 0; epoch < 10; epoch++ {
+		if err := trainer.RunTask(genFFMLines(2000, r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	test := genFFMLines(500, r)
+	dir := t.TempDir()
+	for _, format := range []string{"txt", "bin"} {
+		path := filepath.Join(dir, "ffm."+format)
+		if err := trainer.OutputModel(path, format); err != nil {
+			t.Fatalf("output %s model failed: %v", format, err)
+		}
+
+		pm := NewPredictModel(4)
+		pm.Meta = opt.ModelMeta()
+		if err := pm.LoadModel(path, format); err != nil {
+			t.Fatalf("load %s model failed: %v", format, err)
+		}
+
+		correct := 0
+		for _, line := range test {
+			s, err := sample.ParseFFMSample(line, 3, sample.LabelBinary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			score := pm.GetScoreFFM(s.X, pm.MuBias.Wi)
+			if (score > 0.5) == (s.Y > 0) {
+				correct++
+			}
+		}
+		if acc := float64(correct) / float64(len(test)); acc < 0.95 {
+			t.Errorf("%s model accuracy too low: %.3f", format, acc)
+		}
+
+		fm := NewPredictModel(4)
+		if err := fm.LoadModel(path, format); err == nil {
+			t.Errorf("loading %s ffm model as fm should fail", format)
+		}
+	}
+}
+
+func TestParseFFMSample(t *testing.T) {
+	s, err := sample.ParseFFMSample("1 0:a:1 2:b:0.5 1:c:0", 3, sample.LabelBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != 1 || len(s.X) != 2 || s.X[1].Field != 2 || s.X[1].Feature != "b" || s.X[1].Value != 0.5 {
+		t.Errorf("unexpected sample: %+v", s)
+	}
+	if _, err := sample.ParseFFMSample("1 3:a:1", 3, sample.LabelBinary); err == nil {
+		t.Error("out of range field should fail")
+	}
+	if _, err := sample.ParseFFMSample("1 a:1", 3, sample.LabelBinary); err == nil {
+		t.Error("fm feature should fail")
+	}
+}
@@ -3,12 +3,12 @@ package model
 import (
 	"bufio"
 	"fmt"
-	"math"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/xiongle/alphaFM-go/pkg/sample"
 	"github.com/xiongle/alphaFM-go/pkg/simd"
 	"github.com/xiongle/alphaFM-go/pkg/utils"
 )
@@ -23,18 +23,14 @@ type FTRLModelUnit struct {
 	Vi   []float64 // 隐向量
 	VNi  []float64 // v的n参数
 	VZi  []float64 // v的z参数
+
+	lastSeen  int64 // 最近一次出现时已训练的样本数（开启特征淘汰时记录）
+	lowWeight bool  // 上一次淘汰扫描时权重是否低于阈值
 }
 
 // NewFTRLModelUnit 创建模型单元
 func NewFTRLModelUnit(factorNum int, mean, stdev float64) *FTRLModelUnit {
-	unit := &FTRLModelUnit{
-		Wi:  0.0,
-		WNi: 0.0,
-		WZi: 0.0,
-		Vi:  make([]float64, factorNum),
-		VNi: make([]float64, factorNum),
-		VZi: make([]float64, factorNum),
-	}
+	unit := newFTRLModelUnitVectors(factorNum)
 
 	// 初始化隐向量
 	for f := 0; f < factorNum; f++ {
@@ -46,17 +42,37 @@ func NewFTRLModelUnit(factorNum int, mean, stdev float64) *FTRLModelUnit {
 	return unit
 }
 
+// newFTRLModelUnitVectors 创建零值单元，Vi、VNi、VZi共用一次分配
+func newFTRLModelUnitVectors(vecLen int) *FTRLModelUnit {
+	unit := &FTRLModelUnit{}
+	unit.bindVectors(make([]float64, 3*vecLen))
+	return unit
+}
+
+// bindVectors 把Vi、VNi、VZi依次指向buf中等长的三段
+func (u *FTRLModelUnit) bindVectors(buf []float64) {
+	vecLen := len(buf) / 3
+	u.Vi = buf[:vecLen:vecLen]
+	u.VNi = buf[vecLen : 2*vecLen : 2*vecLen]
+	u.VZi = buf[2*vecLen:]
+}
+
+// copyFrom 复制src的参数，向量复制到u已有的存储中
+func (u *FTRLModelUnit) copyFrom(src *FTRLModelUnit) {
+	u.Wi, u.WNi, u.WZi = src.Wi, src.WNi, src.WZi
+	copy(u.Vi, src.Vi)
+	copy(u.VNi, src.VNi)
+	copy(u.VZi, src.VZi)
+	u.lastSeen, u.lowWeight = src.lastSeen, src.lowWeight
+}
+
 // NewFTRLModelUnitFromLine 从模型文件行创建
 func NewFTRLModelUnitFromLine(factorNum int, parts []string) (*FTRLModelUnit, error) {
 	if len(parts) != 3*factorNum+4 {
 		return nil, fmt.Errorf("invalid model line format")
 	}
 
-	unit := &FTRLModelUnit{
-		Vi:  make([]float64, factorNum),
-		VNi: make([]float64, factorNum),
-		VZi: make([]float64, factorNum),
-	}
+	unit := newFTRLModelUnitVectors(factorNum)
 
 	var err error
 	unit.Wi, err = strconv.ParseFloat(parts[1], 64)
@@ -101,6 +117,21 @@ func NewFTRLModelUnitFromLine(factorNum int, parts []string) (*FTRLModelUnit, er
 	return unit, nil
 }
 
+// Clone 深拷贝
+func (u *FTRLModelUnit) Clone() *FTRLModelUnit {
+	return &FTRLModelUnit{
+		Wi:  u.Wi,
+		WNi: u.WNi,
+		WZi: u.WZi,
+		Vi:  append([]float64(nil), u.Vi...),
+		VNi: append([]float64(nil), u.VNi...),
+		VZi: append([]float64(nil), u.VZi...),
+
+		lastSeen:  u.lastSeen,
+		lowWeight: u.lowWeight,
+	}
+}
+
 // ReinitVi 重新初始化隐向量
 func (u *FTRLModelUnit) ReinitVi(mean, stdev float64) {
 	for f := 0; f < len(u.Vi); f++ {
@@ -150,52 +181,154 @@ func (u *FTRLModelUnit) String() string {
 // FTRLModel FTRL模型
 type FTRLModel struct {
 	MuBias    *FTRLModelUnit
-	MuMap     map[string]*FTRLModelUnit
 	FactorNum int
 	InitMean  float64
 	InitStdev float64
-	mu        sync.RWMutex
+	Meta      ModelMeta
+	store     *unitStore[FTRLModelUnit]
+	mu        sync.Mutex // 保护bias单元的创建
+	initSeed  uint64     // 按特征确定性初始化的种子
+	seeded    bool       // 是否按特征确定性初始化
+	admission Admission  // 新特征的准入策略，nil表示全部准入
 }
 
 // NewFTRLModel 创建FTRL模型
 func NewFTRLModel(factorNum int, mean, stdev float64) *FTRLModel {
 	return &FTRLModel{
-		MuMap:     make(map[string]*FTRLModelUnit),
 		FactorNum: factorNum,
 		InitMean:  mean,
 		InitStdev: stdev,
+		Meta:      NewModelMeta(),
+		store:     newUnitStore[FTRLModelUnit](nil, defaultShardNum),
 	}
 }
 
-// GetOrInitModelUnit 获取或初始化模型单元
-func (m *FTRLModel) GetOrInitModelUnit(feature string) *FTRLModelUnit {
-	m.mu.RLock()
-	unit, exists := m.MuMap[feature]
-	m.mu.RUnlock()
+// SetMeta 设置元信息并按其中的特征哈希配置重建特征存储，只能在模型为空时调用
+// 限定哈希桶数时特征存储为按桶数预分配的数组
+func (m *FTRLModel) SetMeta(meta ModelMeta) {
+	m.Meta = meta
+	m.store = newUnitStore[FTRLModelUnit](meta.Hasher(), defaultShardNum)
+	if m.store.hasher != nil && m.store.hasher.Buckets > 0 {
+		m.store.useBuckets(3*m.VecLen(), m.bucketHooks())
+	}
+	m.SetAdmission(m.admission)
+}
 
-	if exists {
-		return unit
+// bucketHooks 桶数组模式下单元的操作
+func (m *FTRLModel) bucketHooks() bucketHooks[FTRLModelUnit] {
+	return bucketHooks[FTRLModelUnit]{
+		bind: (*FTRLModelUnit).bindVectors,
+		init: m.initUnit,
+		copy: (*FTRLModelUnit).copyFrom,
 	}
+}
+
+// SetAdmission 设置新特征的准入策略，nil表示全部准入；已有参数的特征不受影响
+func (m *FTRLModel) SetAdmission(admission Admission) {
+	m.admission = admission
+	if admission != nil {
+		m.store.admit = admission.Admit
+	} else {
+		m.store.admit = nil
+	}
+}
+
+// Hasher 特征哈希，未开启时返回nil
+func (m *FTRLModel) Hasher() *FeatureHasher {
+	return m.store.hasher
+}
+
+// FeatureNum 特征单元数
+func (m *FTRLModel) FeatureNum() int {
+	return m.store.len()
+}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// VecLen 每个特征单元的隐向量长度
+func (m *FTRLModel) VecLen() int {
+	return m.Meta.VecLen(m.FactorNum)
+}
 
-	// 双重检查
-	if unit, exists := m.MuMap[feature]; exists {
+// newUnit 创建新的特征单元，id为特征名的哈希（特征哈希模式下为键）
+func (m *FTRLModel) newUnit(id uint64) *FTRLModelUnit {
+	if !m.seeded {
+		unit := NewFTRLModelUnit(m.VecLen(), m.InitMean, m.InitStdev)
+		m.zeroClassWeights(unit)
 		return unit
 	}
+	unit := newFTRLModelUnitVectors(m.VecLen())
+	m.initUnit(id, unit)
+	return unit
+}
 
-	unit = NewFTRLModelUnit(m.FactorNum, m.InitMean, m.InitStdev)
-	m.MuMap[feature] = unit
+// initUnit 初始化向量已分配（全为0）的新单元，与newUnit的初始化相同
+func (m *FTRLModel) initUnit(id uint64, unit *FTRLModelUnit) {
+	if !m.seeded {
+		unit.ReinitVi(m.InitMean, m.InitStdev)
+	} else {
+		newInitRNG(m.initSeed, id).fill(unit.Vi, m.InitMean, m.InitStdev)
+	}
+	m.zeroClassWeights(unit)
+}
+
+// zeroClassWeights 多分类模型各类别的w与一阶权重一样初始为0
+func (m *FTRLModel) zeroClassWeights(unit *FTRLModelUnit) {
+	for c := 0; c < m.Meta.ClassNum; c++ {
+		unit.Vi[c] = 0
+	}
+}
+
+// GetOrInitModelUnit 获取或初始化模型单元，特征未通过准入时返回nil
+func (m *FTRLModel) GetOrInitModelUnit(feature string) *FTRLModelUnit {
+	unit, _ := m.store.getOrInit(feature, m.newUnit)
 	return unit
 }
 
+// GetOrInitModelUnitWithLock 获取或初始化模型单元，同时返回其所在分片的更新锁
+func (m *FTRLModel) GetOrInitModelUnitWithLock(feature string) (*FTRLModelUnit, *sync.Mutex) {
+	return m.store.getOrInit(feature, m.newUnit)
+}
+
+// GetOrInitModelUnitIndexed 获取或初始化模型单元，同时返回其所在分片的序号
+func (m *FTRLModel) GetOrInitModelUnitIndexed(feature string) (*FTRLModelUnit, int) {
+	return m.store.getOrInitIndex(feature, m.newUnit)
+}
+
+// ShardNum 特征存储的分片数
+func (m *FTRLModel) ShardNum() int {
+	return m.store.shardNum()
+}
+
+// GetModelUnit 获取模型单元，不存在时不创建
+func (m *FTRLModel) GetModelUnit(feature string) (*FTRLModelUnit, bool) {
+	return m.store.get(feature)
+}
+
+// Clone 深拷贝模型，调用时不能有并发的训练更新
+func (m *FTRLModel) Clone() *FTRLModel {
+	c := &FTRLModel{
+		FactorNum: m.FactorNum,
+		InitMean:  m.InitMean,
+		InitStdev: m.InitStdev,
+		Meta:      m.Meta,
+		store:     m.store.clone((*FTRLModelUnit).Clone),
+		initSeed:  m.initSeed,
+		seeded:    m.seeded,
+	}
+	if c.store.buckets != nil {
+		c.store.buckets.hooks = c.bucketHooks()
+	}
+	if m.MuBias != nil {
+		c.MuBias = m.MuBias.Clone()
+	}
+	return c
+}
+
 // GetOrInitModelUnitBias 获取或初始化bias单元
 func (m *FTRLModel) GetOrInitModelUnitBias() *FTRLModelUnit {
 	if m.MuBias == nil {
 		m.mu.Lock()
 		if m.MuBias == nil {
-			m.MuBias = NewFTRLModelUnit(0, m.InitMean, m.InitStdev)
+			m.MuBias = newFTRLModelUnitVectors(m.Meta.BiasVecLen())
 		}
 		m.mu.Unlock()
 	}
@@ -294,39 +427,51 @@ func (m *FTRLModel) loadTxtModel(modelPath string) error {
 		return fmt.Errorf("empty model file")
 	}
 
-	parts := strings.Fields(scanner.Text())
-	if len(parts) != 4 {
-		return fmt.Errorf("invalid bias line format")
+	// 非默认模型首行为元信息
+	if isMetaLine(scanner.Text()) {
+		meta, err := parseMetaLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if err := m.Meta.CheckCompatible(meta); err != nil {
+			return err
+		}
+		m.SetMeta(meta)
+		if !scanner.Scan() {
+			return fmt.Errorf("missing bias line")
+		}
+	} else if !m.Meta.IsDefault() {
+		return fmt.Errorf("model meta missing, expected %s", m.Meta.String())
 	}
 
-	m.MuBias = &FTRLModelUnit{}
-	m.MuBias.Wi, err = strconv.ParseFloat(parts[1], 64)
-	if err != nil {
-		return err
-	}
-	m.MuBias.WNi, err = strconv.ParseFloat(parts[2], 64)
-	if err != nil {
-		return err
+	// 多分类模型的bias行带有各类别的bias
+	biasLen := m.Meta.BiasVecLen()
+	parts := strings.Fields(scanner.Text())
+	if len(parts) != 3*biasLen+4 {
+		return fmt.Errorf("invalid bias line format")
 	}
-	m.MuBias.WZi, err = strconv.ParseFloat(parts[3], 64)
+	m.MuBias, err = NewFTRLModelUnitFromLine(biasLen, parts)
 	if err != nil {
 		return err
 	}
 
 	// 读取特征行
+	vecLen := m.VecLen()
 	for scanner.Scan() {
 		parts := strings.Fields(scanner.Text())
-		if len(parts) != 3*m.FactorNum+4 {
+		if len(parts) != 3*vecLen+4 {
 			return fmt.Errorf("invalid feature line format")
 		}
 
 		feature := parts[0]
-		unit, err := NewFTRLModelUnitFromLine(m.FactorNum, parts)
+		unit, err := NewFTRLModelUnitFromLine(vecLen, parts)
 		if err != nil {
 			return err
 		}
 
-		m.MuMap[feature] = unit
+		if err := m.store.set(feature, unit); err != nil {
+			return err
+		}
 	}
 
 	return scanner.Err()
@@ -346,6 +491,11 @@ func (m *FTRLModel) loadBinModel(modelPath string) error {
 	if info.FactorNum != uint64(m.FactorNum) {
 		return fmt.Errorf("factor_num mismatch: model=%d, expected=%d", info.FactorNum, m.FactorNum)
 	}
+	if err := m.Meta.CheckCompatible(mbf.GetMeta()); err != nil {
+		return err
+	}
+	m.SetMeta(mbf.GetMeta())
+	vecLen := m.VecLen()
 
 	// 读取bias
 	feaName, err := mbf.ReadOneFea()
@@ -357,15 +507,16 @@ func (m *FTRLModel) loadBinModel(modelPath string) error {
 	}
 
 	// 根据number_byte_len读取bias unit
-	m.MuBias = &FTRLModelUnit{}
+	biasLen := m.Meta.BiasVecLen()
+	m.MuBias = newFTRLModelUnitVectors(biasLen)
 	if info.NumByteLen == 8 {
 		// double
-		if err := mbf.ReadOneUnitDouble(m.MuBias, 0); err != nil {
+		if err := mbf.ReadOneUnitDouble(m.MuBias, biasLen); err != nil {
 			return fmt.Errorf("failed to read bias unit: %v", err)
 		}
 	} else if info.NumByteLen == 4 {
 		// float
-		if err := mbf.ReadOneUnitFloat(m.MuBias, 0); err != nil {
+		if err := mbf.ReadOneUnitFloat(m.MuBias, biasLen); err != nil {
 			return fmt.Errorf("failed to read bias unit: %v", err)
 		}
 	} else {
@@ -382,23 +533,21 @@ func (m *FTRLModel) loadBinModel(modelPath string) error {
 			return fmt.Errorf("failed to read feature name: %v", err)
 		}
 
-		unit := &FTRLModelUnit{
-			Vi:  make([]float64, m.FactorNum),
-			VNi: make([]float64, m.FactorNum),
-			VZi: make([]float64, m.FactorNum),
-		}
+		unit := newFTRLModelUnitVectors(vecLen)
 
 		if info.NumByteLen == 8 {
-			if err := mbf.ReadOneUnitDouble(unit, m.FactorNum); err != nil {
+			if err := mbf.ReadOneUnitDouble(unit, vecLen); err != nil {
 				return fmt.Errorf("failed to read unit for %s: %v", feaName, err)
 			}
 		} else if info.NumByteLen == 4 {
-			if err := mbf.ReadOneUnitFloat(unit, m.FactorNum); err != nil {
+			if err := mbf.ReadOneUnitFloat(unit, vecLen); err != nil {
 				return fmt.Errorf("failed to read unit for %s: %v", feaName, err)
 			}
 		}
 
-		m.MuMap[feaName] = unit
+		if err := m.store.set(feaName, unit); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -406,68 +555,101 @@ func (m *FTRLModel) loadBinModel(modelPath string) error {
 
 // OutputModel 输出模型
 func (m *FTRLModel) OutputModel(modelPath, modelFormat string) error {
+	return m.outputModel(modelPath, modelFormat, false)
+}
+
+// outputModel 输出模型，durable为true时关闭文件前把数据刷到磁盘
+func (m *FTRLModel) outputModel(modelPath, modelFormat string, durable bool) error {
 	if modelFormat == "txt" {
-		return m.outputTxtModel(modelPath)
+		return m.outputTxtModel(modelPath, durable)
 	} else if modelFormat == "bin" {
-		return m.outputBinModel(modelPath)
+		return m.outputBinModel(modelPath, durable)
 	}
 	return fmt.Errorf("unsupported model format: %s", modelFormat)
 }
 
-// outputTxtModel 输出文本模型
-func (m *FTRLModel) outputTxtModel(modelPath string) error {
+// outputTxtModel 输出文本模型，写入、刷新和关闭文件的错误都会返回
+func (m *FTRLModel) outputTxtModel(modelPath string, durable bool) error {
 	file, err := os.Create(modelPath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
 	writer := bufio.NewWriter(file)
-	defer writer.Flush()
+	err = m.writeTxtModel(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil && durable {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeTxtModel 按文本格式写出模型
+func (m *FTRLModel) writeTxtModel(writer *bufio.Writer) error {
+	// 非默认模型先输出元信息
+	if !m.Meta.IsDefault() {
+		fmt.Fprintln(writer, metaLine(m.Meta))
+	}
 
 	// 输出bias
-	fmt.Fprintf(writer, "%s %.6g %.6g %.6g\n", BiasFeatureName, m.MuBias.Wi, m.MuBias.WNi, m.MuBias.WZi)
+	fmt.Fprintf(writer, "%s %s\n", BiasFeatureName, m.MuBias.String())
 
 	// 输出特征
-	for feature, unit := range m.MuMap {
-		fmt.Fprintf(writer, "%s %s\n", feature, unit.String())
-	}
-
-	return nil
+	return m.store.forEach(func(feature string, unit *FTRLModelUnit) error {
+		_, err := fmt.Fprintf(writer, "%s %s\n", feature, unit.String())
+		return err
+	})
 }
 
 // outputBinModel 输出二进制模型
-func (m *FTRLModel) outputBinModel(modelPath string) error {
+// 写入失败时直接关闭文件，不写成功标志，未写完的文件不会被当作完整模型加载
+func (m *FTRLModel) outputBinModel(modelPath string, durable bool) error {
 	// 计算unit长度: wi(8) + w_ni(8) + w_zi(8) + vi(8*k) + v_ni(8*k) + v_zi(8*k)
-	unitLen := uint64(3*8 + 3*m.FactorNum*8)
+	unitLen := uint64(3*8 + 3*m.VecLen()*8)
 
 	mbf := NewModelBinFile()
-	if err := mbf.OpenForWrite(modelPath, 8, uint64(m.FactorNum), unitLen); err != nil {
+	if err := mbf.OpenForWriteWithMeta(modelPath, 8, uint64(m.FactorNum), unitLen, m.Meta); err != nil {
+		if mbf.file != nil {
+			mbf.file.Close()
+		}
 		return err
 	}
-	defer mbf.Close()
 
-	// 写入bias (factor_num = 0，所以没有v向量)
-	if err := mbf.WriteOneFeaUnitDouble(BiasFeatureName, m.MuBias, 0, true); err != nil {
+	// 写入bias (没有v向量，多分类模型为各类别的bias)
+	if err := mbf.WriteOneFeaUnitDouble(BiasFeatureName, m.MuBias, m.Meta.BiasVecLen(), true); err != nil {
+		mbf.file.Close()
 		return fmt.Errorf("failed to write bias: %v", err)
 	}
 
-	// 写入特征 (factor_num = m.FactorNum)
-	for feature, unit := range m.MuMap {
+	// 写入特征 (向量长度为m.VecLen())
+	err := m.store.forEach(func(feature string, unit *FTRLModelUnit) error {
 		isNonZero := unit.IsNonZero()
-		if err := mbf.WriteOneFeaUnitDouble(feature, unit, m.FactorNum, isNonZero); err != nil {
+		if err := mbf.WriteOneFeaUnitDouble(feature, unit, m.VecLen(), isNonZero); err != nil {
 			return fmt.Errorf("failed to write feature %s: %v", feature, err)
 		}
+		return nil
+	})
+	if err != nil {
+		mbf.file.Close()
+		return err
 	}
-
-	return nil
+	if durable {
+		return mbf.CloseSync()
+	}
+	return mbf.Close()
 }
 
 // PredictModel 预测模型（简化版，只包含wi和vi）
 type PredictModel struct {
 	MuBias    *PredictModelUnit
-	MuMap     map[string]*PredictModelUnit
 	FactorNum int
+	Meta      ModelMeta
+	store     *unitStore[PredictModelUnit]
 }
 
 // PredictModelUnit 预测模型单元
@@ -479,19 +661,43 @@ type PredictModelUnit struct {
 // NewPredictModel 创建预测模型
 func NewPredictModel(factorNum int) *PredictModel {
 	return &PredictModel{
-		MuMap:     make(map[string]*PredictModelUnit),
 		FactorNum: factorNum,
+		Meta:      NewModelMeta(),
+		store:     newUnitStore[PredictModelUnit](nil, defaultShardNum),
 	}
 }
 
-// GetScore 计算预测得分（包含sigmoid）
+// SetMeta 设置元信息并按其中的特征哈希配置重建特征存储，只能在模型为空时调用
+func (m *PredictModel) SetMeta(meta ModelMeta) {
+	m.Meta = meta
+	m.store = newUnitStore[PredictModelUnit](meta.Hasher(), defaultShardNum)
+}
+
+// FeatureNum 特征单元数
+func (m *PredictModel) FeatureNum() int {
+	return m.store.len()
+}
+
+// GetUnit 获取特征单元
+func (m *PredictModel) GetUnit(feature string) (*PredictModelUnit, bool) {
+	return m.store.get(feature)
+}
+
+// VecLen 每个特征单元的隐向量长度
+func (m *PredictModel) VecLen() int {
+	return m.Meta.VecLen(m.FactorNum)
+}
+
+// GetScore 计算预测得分（经过训练目标的link函数，logistic为sigmoid）
 func (m *PredictModel) GetScore(x []struct{ Feature string; Value float64 }, bias float64) float64 {
 	result := bias
 
-	// 一阶项
+	// 一阶项，同时记录各特征的单元，不在模型中的特征忽略
+	units := make([]*PredictModelUnit, len(x))
 	for i := 0; i < len(x); i++ {
-		if unit, ok := m.MuMap[x[i].Feature]; ok {
+		if unit, ok := m.store.get(x[i].Feature); ok {
 			result += unit.Wi * x[i].Value
+			units[i] = unit
 		}
 	}
 
@@ -500,7 +706,7 @@ func (m *PredictModel) GetScore(x []struct{ Feature string; Value float64 }, bia
 		sumF := 0.0
 		sumSqr := 0.0
 		for i := 0; i < len(x); i++ {
-			if unit, ok := m.MuMap[x[i].Feature]; ok {
+			if unit := units[i]; unit != nil {
 				d := unit.Vi[f] * x[i].Value
 				sumF += d
 				sumSqr += d * d
@@ -509,11 +715,29 @@ func (m *PredictModel) GetScore(x []struct{ Feature string; Value float64 }, bia
 		result += 0.5 * (sumF*sumF - sumSqr)
 	}
 
-	// Sigmoid
-	return 1.0 / (1.0 + math.Exp(-result))
+	return m.loss().Link(result)
 }
 
-// GetScoreSIMD 计算预测得分（包含sigmoid，使用SIMD优化）
+// loss 模型元信息中记录的训练目标
+func (m *PredictModel) loss() Loss {
+	return mustLoss(m.Meta.Loss)
+}
+
+// checkMeta 检查模型文件的元信息与期望是否一致，期望的ClassNum为0时不检查，类别数以模型为准
+func (m *PredictModel) checkMeta(loaded ModelMeta) error {
+	expected := m.Meta
+	if expected.ClassNum == 0 {
+		expected.ClassNum = loaded.ClassNum
+	}
+	return expected.CheckCompatible(loaded)
+}
+
+// Calibrate 按元信息中的负样本采样率校准预测概率，模型未降采样时原样返回
+func (m *PredictModel) Calibrate(p float64) float64 {
+	return CorrectNegSampling(p, m.Meta.NegSampleRate)
+}
+
+// GetScoreSIMD 计算预测得分（经过link函数，使用SIMD优化）
 func (m *PredictModel) GetScoreSIMD(x []struct{ Feature string; Value float64 }, bias float64, ops simd.VectorOps) float64 {
 	result := bias
 
@@ -522,7 +746,7 @@ func (m *PredictModel) GetScoreSIMD(x []struct{ Feature string; Value float64 },
 	validValues := make([]float64, 0, len(x))
 	
 	for i := 0; i < len(x); i++ {
-		if unit, ok := m.MuMap[x[i].Feature]; ok {
+		if unit, ok := m.store.get(x[i].Feature); ok {
 			// 一阶项
 			result += unit.Wi * x[i].Value
 			validUnits = append(validUnits, unit)
@@ -531,7 +755,7 @@ func (m *PredictModel) GetScoreSIMD(x []struct{ Feature string; Value float64 },
 	}
 	
 	if len(validUnits) == 0 {
-		return 1.0 / (1.0 + math.Exp(-result))
+		return m.loss().Link(result)
 	}
 
 	// 二阶交互项 - 使用SIMD优化
@@ -558,8 +782,41 @@ func (m *PredictModel) GetScoreSIMD(x []struct{ Feature string; Value float64 },
 	
 	result += 0.5 * (sumTotal - sumSqrTotal)
 
-	// Sigmoid
-	return 1.0 / (1.0 + math.Exp(-result))
+	return m.loss().Link(result)
+}
+
+// GetScoreFFM 计算FFM预测得分（经过link函数），不在模型中的特征忽略
+func (m *PredictModel) GetScoreFFM(x []sample.FeatureValue, bias float64) float64 {
+	result := bias
+	k := m.FactorNum
+
+	units := make([]*PredictModelUnit, 0, len(x))
+	xs := make([]sample.FeatureValue, 0, len(x))
+	for i := 0; i < len(x); i++ {
+		if unit, ok := m.store.get(x[i].Feature); ok {
+			// 一阶项
+			result += unit.Wi * x[i].Value
+			units = append(units, unit)
+			xs = append(xs, x[i])
+		}
+	}
+
+	// 二阶交互项: Σ_{i<j} <v_{i,fj}, v_{j,fi}> * xi * xj
+	for i := 0; i < len(units); i++ {
+		fi := xs[i].Field
+		for j := i + 1; j < len(units); j++ {
+			fj := xs[j].Field
+			vi := units[i].Vi[fj*k : (fj+1)*k]
+			vj := units[j].Vi[fi*k : (fi+1)*k]
+			dot := 0.0
+			for f := 0; f < k; f++ {
+				dot += vi[f] * vj[f]
+			}
+			result += dot * xs[i].Value * xs[j].Value
+		}
+	}
+
+	return m.loss().Link(result)
 }
 
 // LoadModel 加载模型
@@ -587,26 +844,52 @@ func (m *PredictModel) loadTxtModel(modelPath string) error {
 		return fmt.Errorf("empty model file")
 	}
 
+	// 非默认模型首行为元信息
+	if isMetaLine(scanner.Text()) {
+		meta, err := parseMetaLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if err := m.checkMeta(meta); err != nil {
+			return err
+		}
+		m.SetMeta(meta)
+		if !scanner.Scan() {
+			return fmt.Errorf("missing bias line")
+		}
+	} else if !m.Meta.IsDefault() {
+		return fmt.Errorf("model meta missing, expected %s", m.Meta.String())
+	}
+
+	// 多分类模型的bias行带有各类别的bias，保存在Vi中
+	biasLen := m.Meta.BiasVecLen()
 	parts := strings.Fields(scanner.Text())
-	if len(parts) != 4 {
+	if len(parts) != 3*biasLen+4 {
 		return fmt.Errorf("invalid bias line")
 	}
 
-	m.MuBias = &PredictModelUnit{Vi: make([]float64, 0)}
+	m.MuBias = &PredictModelUnit{Vi: make([]float64, biasLen)}
 	m.MuBias.Wi, err = strconv.ParseFloat(parts[1], 64)
 	if err != nil {
 		return err
 	}
+	for c := 0; c < biasLen; c++ {
+		m.MuBias.Vi[c], err = strconv.ParseFloat(parts[2+c], 64)
+		if err != nil {
+			return err
+		}
+	}
 
 	// 读取特征
+	vecLen := m.VecLen()
 	for scanner.Scan() {
 		parts := strings.Fields(scanner.Text())
-		if len(parts) != 3*m.FactorNum+4 {
+		if len(parts) != 3*vecLen+4 {
 			return fmt.Errorf("invalid feature line")
 		}
 
 		feature := parts[0]
-		unit := &PredictModelUnit{Vi: make([]float64, m.FactorNum)}
+		unit := &PredictModelUnit{Vi: make([]float64, vecLen)}
 
 		unit.Wi, err = strconv.ParseFloat(parts[1], 64)
 		if err != nil {
@@ -614,7 +897,7 @@ func (m *PredictModel) loadTxtModel(modelPath string) error {
 		}
 
 		isNonZero := unit.Wi != 0.0
-		for f := 0; f < m.FactorNum; f++ {
+		for f := 0; f < vecLen; f++ {
 			unit.Vi[f], err = strconv.ParseFloat(parts[2+f], 64)
 			if err != nil {
 				return err
@@ -626,7 +909,9 @@ func (m *PredictModel) loadTxtModel(modelPath string) error {
 
 		// 只加载非零特征
 		if isNonZero {
-			m.MuMap[feature] = unit
+			if err := m.store.set(feature, unit); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -647,6 +932,11 @@ func (m *PredictModel) loadBinModel(modelPath string) error {
 	if info.FactorNum != uint64(m.FactorNum) {
 		return fmt.Errorf("factor_num mismatch: model=%d, expected=%d", info.FactorNum, m.FactorNum)
 	}
+	if err := m.checkMeta(mbf.GetMeta()); err != nil {
+		return err
+	}
+	m.SetMeta(mbf.GetMeta())
+	vecLen := m.VecLen()
 
 	// 读取bias
 	feaName, err := mbf.ReadOneFea()
@@ -658,13 +948,14 @@ func (m *PredictModel) loadBinModel(modelPath string) error {
 	}
 
 	// 读取bias unit（预测模型只需要wi，不需要n和z）
-	biasUnit := &FTRLModelUnit{}
+	biasLen := m.Meta.BiasVecLen()
+	biasUnit := newFTRLModelUnitVectors(biasLen)
 	if info.NumByteLen == 8 {
-		if err := mbf.ReadOneUnitDouble(biasUnit, 0); err != nil {
+		if err := mbf.ReadOneUnitDouble(biasUnit, biasLen); err != nil {
 			return fmt.Errorf("failed to read bias unit: %v", err)
 		}
 	} else if info.NumByteLen == 4 {
-		if err := mbf.ReadOneUnitFloat(biasUnit, 0); err != nil {
+		if err := mbf.ReadOneUnitFloat(biasUnit, biasLen); err != nil {
 			return fmt.Errorf("failed to read bias unit: %v", err)
 		}
 	} else {
@@ -673,7 +964,7 @@ func (m *PredictModel) loadBinModel(modelPath string) error {
 	
 	m.MuBias = &PredictModelUnit{
 		Wi: biasUnit.Wi,
-		Vi: make([]float64, 0),
+		Vi: biasUnit.Vi,
 	}
 
 	// 读取特征
@@ -687,17 +978,17 @@ func (m *PredictModel) loadBinModel(modelPath string) error {
 		}
 
 		fullUnit := &FTRLModelUnit{
-			Vi:  make([]float64, m.FactorNum),
-			VNi: make([]float64, m.FactorNum),
-			VZi: make([]float64, m.FactorNum),
+			Vi:  make([]float64, vecLen),
+			VNi: make([]float64, vecLen),
+			VZi: make([]float64, vecLen),
 		}
 
 		if info.NumByteLen == 8 {
-			if err := mbf.ReadOneUnitDouble(fullUnit, m.FactorNum); err != nil {
+			if err := mbf.ReadOneUnitDouble(fullUnit, vecLen); err != nil {
 				return fmt.Errorf("failed to read unit for %s: %v", feaName, err)
 			}
 		} else if info.NumByteLen == 4 {
-			if err := mbf.ReadOneUnitFloat(fullUnit, m.FactorNum); err != nil {
+			if err := mbf.ReadOneUnitFloat(fullUnit, vecLen); err != nil {
 				return fmt.Errorf("failed to read unit for %s: %v", feaName, err)
 			}
 		}
@@ -715,10 +1006,13 @@ func (m *PredictModel) loadBinModel(modelPath string) error {
 
 		// 只加载非零特征
 		if isNonZero {
-			m.MuMap[feaName] = &PredictModelUnit{
+			unit := &PredictModelUnit{
 				Wi: fullUnit.Wi,
 				Vi: fullUnit.Vi,
 			}
+			if err := m.store.set(feaName, unit); err != nil {
+				return err
+			}
 		}
 	}
 
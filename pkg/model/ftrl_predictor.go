@@ -2,10 +2,14 @@ package model
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/xiongle/alphaFM-go/pkg/metrics"
 	"github.com/xiongle/alphaFM-go/pkg/sample"
 	"github.com/xiongle/alphaFM-go/pkg/simd"
 )
@@ -19,6 +23,16 @@ type PredictorOption struct {
 	ThreadsNum      int
 	FactorNum       int
 	SIMDType        simd.VectorOpsType // SIMD优化类型
+	ModelType       string             // fm 或 ffm
+	FieldNum        int                // FFM的field数量
+	ClassNum        int                // 期望的多分类类别数，0表示不检查，以模型元信息为准
+	Eval            bool               // 是否同时计算评估指标
+	GroupPrefix     string             // 以该前缀开头的特征名作为GAUC的分组键
+	GroupColumn     bool               // 每行首列为GAUC的分组键
+	EvalOption      *metrics.EvaluatorOption
+	NegSampleRate   float64 // 负样本采样率校准，0表示使用模型元信息中记录的值
+	Explain         bool    // 每行输出样本得分分解的JSON，代替预测值
+	ExplainTopN     int     // 得分分解中输出的交互项个数
 }
 
 // NewPredictorOption 创建默认预测选项
@@ -29,17 +43,33 @@ func NewPredictorOption() *PredictorOption {
 		ModelFormat:     "txt",
 		ModelNumberType: "double",
 		SIMDType:        simd.VectorOpsScalar, // 默认不使用SIMD
+		ModelType:       ModelTypeFM,
+		EvalOption:      metrics.NewEvaluatorOption(),
 	}
 }
 
+// ModelMeta 根据选项生成期望的模型元信息
+func (opt *PredictorOption) ModelMeta() ModelMeta {
+	meta := NewModelMeta()
+	if opt.ModelType == ModelTypeFFM {
+		meta.ModelType = ModelTypeFFM
+		meta.FieldNum = opt.FieldNum
+	}
+	meta.ClassNum = opt.ClassNum
+	return meta
+}
+
 // FTRLPredictor FTRL预测器
 type FTRLPredictor struct {
-	model    *PredictModel
-	opt      *PredictorOption
-	outFile  *os.File
-	outMu    sync.Mutex
-	simdOps  simd.VectorOps // SIMD运算实例
-	useSIMD  bool           // 是否使用SIMD
+	model      *PredictModel
+	opt        *PredictorOption
+	outFile    *os.File
+	outMu      sync.Mutex
+	simdOps    simd.VectorOps     // SIMD运算实例
+	useSIMD    bool               // 是否使用SIMD
+	evaluator  *metrics.Evaluator // 评估指标，由outMu保护
+	regression bool               // 模型的训练目标不是logistic，标签为实数
+	labelKind  sample.LabelKind   // 训练目标要求的标签取值范围
 }
 
 // NewFTRLPredictor 创建预测器
@@ -48,6 +78,7 @@ func NewFTRLPredictor(opt *PredictorOption) (*FTRLPredictor, error) {
 		model: NewPredictModel(opt.FactorNum),
 		opt:   opt,
 	}
+	p.model.Meta = opt.ModelMeta()
 
 	// 初始化SIMD
 	if opt.SIMDType != simd.VectorOpsScalar {
@@ -72,13 +103,55 @@ func NewFTRLPredictor(opt *PredictorOption) (*FTRLPredictor, error) {
 		return nil, fmt.Errorf("load model error: %v", err)
 	}
 	fmt.Println("model loading finished")
+	if p.model.Meta.Hashed {
+		fmt.Printf("feature hashing enabled, seed: %d, buckets: %d\n", p.model.Meta.HashSeed, p.model.Meta.HashBuckets)
+	}
+	// 回归和计数目标：预测值经过对应的link函数，评估只计算MSE和均值
+	p.regression = p.model.Meta.Loss != LossLogistic
+	// 多分类的类别号和分组评估（NDCG）的相关度等级可以是任意整数
+	p.labelKind = LabelKind(p.model.Meta.Loss, p.model.Meta.ClassNum > 0 || opt.GroupColumn)
+	if p.regression {
+		fmt.Printf("loss: %s\n", p.model.Meta.Loss)
+		if opt.NegSampleRate > 0 {
+			return nil, fmt.Errorf("neg_sample_rate only applies to the logistic loss, model loss: %s", p.model.Meta.Loss)
+		}
+		if opt.EvalOption.Group {
+			return nil, fmt.Errorf("gauc requires a logistic model, model loss: %s", p.model.Meta.Loss)
+		}
+		opt.EvalOption.Regression = true
+	}
+	// 多分类：输出各类别的概率，评估只计算logloss和准确率
+	if p.model.Meta.ClassNum > 0 {
+		if opt.NegSampleRate > 0 {
+			return nil, fmt.Errorf("neg_sample_rate does not apply to multiclass models")
+		}
+		if opt.EvalOption.Group {
+			return nil, fmt.Errorf("gauc does not apply to multiclass models")
+		}
+		opt.EvalOption.ClassNum = p.model.Meta.ClassNum
+		if opt.Explain {
+			return nil, fmt.Errorf("explain does not support multiclass models")
+		}
+	}
+	if opt.NegSampleRate > 0 {
+		p.model.Meta.NegSampleRate = opt.NegSampleRate
+	}
+	if rate := p.model.Meta.NegSampleRate; rate > 0 && rate < 1 {
+		fmt.Printf("negative downsampling correction enabled, rate: %g\n", rate)
+	}
 
-	// 打开输出文件
-	f, err := os.Create(opt.PredictPath)
-	if err != nil {
-		return nil, fmt.Errorf("open output file error: %v", err)
+	// 打开输出文件，评估模式下可以不输出预测结果
+	if opt.PredictPath != "" {
+		f, err := os.Create(opt.PredictPath)
+		if err != nil {
+			return nil, fmt.Errorf("open output file error: %v", err)
+		}
+		p.outFile = f
+	}
+
+	if opt.Eval {
+		p.evaluator = metrics.NewEvaluator(opt.EvalOption)
 	}
-	p.outFile = f
 
 	return p, nil
 }
@@ -86,34 +159,84 @@ func NewFTRLPredictor(opt *PredictorOption) (*FTRLPredictor, error) {
 // RunTask 处理一批数据
 func (p *FTRLPredictor) RunTask(dataBuffer []string) error {
 	results := make([]string, len(dataBuffer))
+	isFFM := p.opt.ModelType == ModelTypeFFM
+	var evaluator *metrics.Evaluator
+	if p.evaluator != nil {
+		evaluator = metrics.NewEvaluator(p.opt.EvalOption)
+	}
 
 	for i, line := range dataBuffer {
-		s, err := sample.ParseSample(line)
+		var group string
+		if p.opt.GroupColumn {
+			var err error
+			group, line, err = sample.SplitGroupColumn(line)
+			if err != nil {
+				fmt.Printf("Warning: skip invalid sample: %v\n", err)
+				continue
+			}
+		}
+
+		var s *sample.FMSample
+		var err error
+		if isFFM {
+			s, err = sample.ParseFFMSample(line, p.opt.FieldNum, p.labelKind)
+		} else {
+			s, err = sample.ParseSample(line, p.labelKind)
+		}
 		if err != nil {
 			fmt.Printf("Warning: skip invalid sample: %v\n", err)
 			continue
 		}
 
-		// 转换特征格式
-		xForPredict := make([]struct{ Feature string; Value float64 }, len(s.X))
-		for j := 0; j < len(s.X); j++ {
-			xForPredict[j].Feature = s.X[j].Feature
-			xForPredict[j].Value = s.X[j].Value
+		if classNum := p.model.Meta.ClassNum; classNum > 0 {
+			label, err := ClassLabel(s.Label, classNum)
+			if err != nil {
+				fmt.Printf("Warning: skip invalid sample: %v\n", err)
+				continue
+			}
+			probs := p.model.GetClassProbs(s.X)
+			results[i] = formatClassProbs(label, probs)
+			if evaluator != nil {
+				evaluator.AddClass(probs, label, s.W)
+			}
+			continue
 		}
 
-		var score float64
-		if p.useSIMD {
-			score = p.model.GetScoreSIMD(xForPredict, p.model.MuBias.Wi, p.simdOps)
-		} else {
-			score = p.model.GetScore(xForPredict, p.model.MuBias.Wi)
+		if p.opt.Explain {
+			if results[i], err = p.formatExplanation(s); err != nil {
+				fmt.Printf("Warning: skip sample: %v\n", err)
+			}
+			continue
+		}
+
+		score := p.predict(s)
+		if p.regression {
+			results[i] = fmt.Sprintf("%g %.6g", s.Label, score)
+			if evaluator != nil {
+				evaluator.Add(score, s.Label, s.W)
+			}
+			continue
 		}
 		results[i] = fmt.Sprintf("%d %.6g", s.Y, score)
+		if evaluator != nil {
+			if p.opt.GroupPrefix != "" {
+				group = sample.GroupKeyByPrefix(s.X, p.opt.GroupPrefix)
+			}
+			evaluator.AddGroup(group, score, binaryLabel(s.Y), s.W)
+		}
 	}
 
 	// 写入结果
 	p.outMu.Lock()
 	defer p.outMu.Unlock()
 
+	if evaluator != nil {
+		p.evaluator.Merge(evaluator)
+	}
+	if p.outFile == nil {
+		return nil
+	}
+
 	writer := bufio.NewWriter(p.outFile)
 	for _, result := range results {
 		if result != "" {
@@ -125,6 +248,82 @@ func (p *FTRLPredictor) RunTask(dataBuffer []string) error {
 	return nil
 }
 
+// predict 计算样本的预测值（logistic目标为概率，按负样本采样率校准）
+func (p *FTRLPredictor) predict(s *sample.FMSample) float64 {
+	return p.model.Calibrate(p.rawScore(s))
+}
+
+// rawScore 计算样本未经校准的预测值
+func (p *FTRLPredictor) rawScore(s *sample.FMSample) float64 {
+	if p.opt.ModelType == ModelTypeFFM {
+		return p.model.GetScoreFFM(s.X, p.model.MuBias.Wi)
+	}
+
+	// 转换特征格式
+	xForPredict := make([]struct{ Feature string; Value float64 }, len(s.X))
+	for j := 0; j < len(s.X); j++ {
+		xForPredict[j].Feature = s.X[j].Feature
+		xForPredict[j].Value = s.X[j].Value
+	}
+
+	if p.useSIMD {
+		return p.model.GetScoreSIMD(xForPredict, p.model.MuBias.Wi, p.simdOps)
+	}
+	return p.model.GetScore(xForPredict, p.model.MuBias.Wi)
+}
+
+// formatClassProbs 多分类的输出行：类别标签和各类别的概率
+func formatClassProbs(label int, probs []float64) string {
+	var sb strings.Builder
+	sb.WriteString(strconv.Itoa(label))
+	for _, p := range probs {
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.FormatFloat(p, 'g', 6, 64))
+	}
+	return sb.String()
+}
+
+// explainLine explain模式的输出行
+type explainLine struct {
+	Label float64 `json:"label"`
+	*Explanation
+}
+
+// formatExplanation explain模式的输出行：标签（与普通输出相同）和得分分解的JSON
+func (p *FTRLPredictor) formatExplanation(s *sample.FMSample) (string, error) {
+	e, err := p.model.Explain(s.X, p.opt.ExplainTopN)
+	if err != nil {
+		return "", err
+	}
+	label := s.Label
+	if !p.regression {
+		label = float64(s.Y)
+	}
+	b, err := json.Marshal(explainLine{Label: label, Explanation: e})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// binaryLabel 把-1/1标签转换为0/1
+func binaryLabel(y int) float64 {
+	if y > 0 {
+		return 1.0
+	}
+	return 0.0
+}
+
+// EvalSummary 返回评估结果，未开启评估时返回nil
+func (p *FTRLPredictor) EvalSummary() *metrics.Summary {
+	if p.evaluator == nil {
+		return nil
+	}
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+	return p.evaluator.Summary()
+}
+
 // Close 关闭预测器
 func (p *FTRLPredictor) Close() error {
 	if p.outFile != nil {
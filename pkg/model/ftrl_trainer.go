@@ -4,11 +4,11 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
 
-	"github.com/xiongle/alphaFM-go/pkg/lock"
+	"github.com/xiongle/alphaFM-go/pkg/metrics"
 	"github.com/xiongle/alphaFM-go/pkg/sample"
 	"github.com/xiongle/alphaFM-go/pkg/simd"
-	"github.com/xiongle/alphaFM-go/pkg/utils"
 )
 
 // TrainerOption 训练选项
@@ -35,6 +35,39 @@ type TrainerOption struct {
 	BInit               bool
 	ForceVSparse        bool
 	SIMDType            simd.VectorOpsType // SIMD优化类型
+	ModelType           string             // fm 或 ffm
+	FieldNum            int                // FFM的field数量
+	Optimizer           string             // ftrl, adagrad, adam 或 sgd
+	Loss                string             // 训练目标: logistic, squared 或 poisson
+	Rank                string             // 成对排序训练: bpr 或 ranknet，空表示逐样本训练；输入行首列为分组键
+	RankNeg             int                // bpr每个正样本采样的负样本数
+	ClassNum            int                // 多分类的类别数，0表示不是多分类；标签为类别序号
+	AdamBeta1           float64
+	AdamBeta2           float64
+	AdamEps             float64
+	EpochNum            int    // 训练轮数，大于1时缓存样本回放
+	EpochMemLimit       int64  // 样本缓存的内存上限（字节），超过后溢写到文件
+	EpochCachePath      string // 样本缓存溢写文件路径，为空时使用临时文件
+	Shuffle             bool   // 每轮回放前是否打乱样本
+	ShuffleSeed         int64  // 打乱样本的随机种子
+	PVWindow            int    // 渐进式验证窗口AUC的样本数
+	ValMetric           string // 早停使用的验证指标，logloss、auc或mse
+	ValPatience         int    // 连续多少次验证没有提升后停止训练，0表示不早停
+	Hashed              bool   // 特征哈希模式，特征名映射为64位键
+	HashSeed            uint64 // 特征哈希的种子
+	HashBuckets         uint64 // 特征哈希的桶数，0表示使用完整的64位键
+	HashStats           bool   // 统计特征哈希冲突
+	Hogwild             bool   // 无锁训练，多线程更新同一特征时允许相互覆盖
+	Seed                int64  // 非0时按特征名的哈希确定性初始化隐向量
+	Deterministic       bool   // 批同步训练，结果与线程数无关（仅FM）
+	SyncBatch           int    // 批同步训练的小批大小
+	AdmitMinCount       int     // 特征出现次数达到该值才创建参数，0或1表示不限制
+	AdmitWidth          int     // 出现次数计数的count-min sketch每行计数器个数
+	AdmitProb           float64 // 特征每次出现时被准入的概率，0或1表示不限制
+	EvictStale          int64   // 特征超过该样本数未出现时淘汰，0表示不按时间淘汰
+	EvictMinW           float64 // 与EvictMinV一起，|w|和||v||连续两次扫描都不超过阈值时淘汰
+	EvictMinV           float64 // 两者都为0表示不按权重淘汰
+	NegSampleRate       float64 // 训练数据的负样本采样率，记录在模型元信息中供预测时校准，0表示未降采样
 }
 
 // NewTrainerOption 创建默认训练选项
@@ -60,24 +93,105 @@ func NewTrainerOption() *TrainerOption {
 		ForceVSparse:       false,
 		ModelNumberType:    "double",
 		SIMDType:           simd.VectorOpsScalar, // 默认不使用SIMD
+		ModelType:          ModelTypeFM,
+		Optimizer:          OptimizerFTRL,
+		Loss:               LossLogistic,
+		RankNeg:            1,
+		AdamBeta1:          0.9,
+		AdamBeta2:          0.999,
+		AdamEps:            1e-8,
+		EpochNum:           1,
+		EpochMemLimit:      1024 << 20,
+		ShuffleSeed:        1,
+		PVWindow:           100000,
+		ValMetric:          ValMetricLogLoss,
+		SyncBatch:          256,
+		AdmitWidth:         1 << 20,
 	}
 }
 
+// ModelMeta 根据选项生成模型元信息
+func (opt *TrainerOption) ModelMeta() ModelMeta {
+	meta := NewModelMeta()
+	if opt.ModelType == ModelTypeFFM {
+		meta.ModelType = ModelTypeFFM
+		meta.FieldNum = opt.FieldNum
+	}
+	meta.ClassNum = opt.ClassNum
+	meta.Optimizer = opt.Optimizer
+	meta.Loss = opt.Loss
+	if opt.Hashed {
+		meta.Hashed = true
+		meta.HashSeed = opt.HashSeed
+		meta.HashBuckets = opt.HashBuckets
+	}
+	meta.NegSampleRate = opt.NegSampleRate
+	return meta
+}
+
+// NewAdmission 根据选项创建新特征的准入策略，不限制时返回nil
+func (opt *TrainerOption) NewAdmission() Admission {
+	switch {
+	case opt.AdmitMinCount > 1:
+		return NewCountMinAdmission(opt.AdmitMinCount, opt.AdmitWidth)
+	case opt.AdmitProb > 0 && opt.AdmitProb < 1:
+		return NewPoissonAdmission(opt.AdmitProb, opt.Seed)
+	}
+	return nil
+}
+
 // FTRLTrainer FTRL训练器
 type FTRLTrainer struct {
 	model        *FTRLModel
-	lockPool     *lock.LockPool
+	biasLock     sync.Mutex
 	opt          *TrainerOption
+	optimizer    Optimizer      // 参数更新规则
+	loss         Loss           // 训练目标
+	labelKind    sample.LabelKind // 训练目标要求的标签取值范围
 	simdOps      simd.VectorOps // SIMD运算实例
 	useSIMD      bool           // 是否使用SIMD
+	sampleCache  *sample.SampleCache // 多轮训练时缓存首轮解析的样本
+	lossMu       sync.Mutex
+	lossSum      float64 // 累计训练损失（更新前的预测，按样本权重加权）
+	lossWeight   float64
+	lossNum      int64
+	progressive  *metrics.ProgressiveValidator // 首轮的渐进式验证
+	val          *validationState              // 验证集和早停状态
+	resumeLines  int64                         // 初始模型检查点记录的已训练输入行数
+	seen         int64                         // 已开始训练的样本数，用于特征淘汰
 }
 
 // NewFTRLTrainer 创建训练器
 func NewFTRLTrainer(opt *TrainerOption) *FTRLTrainer {
 	t := &FTRLTrainer{
 		model:    NewFTRLModel(opt.FactorNum, opt.InitMean, opt.InitStdev),
-		lockPool: lock.NewLockPool(),
-		opt:      opt,
+		opt:         opt,
+		progressive: metrics.NewProgressiveValidator(opt.PVWindow),
+	}
+	// 初始化优化器
+	optimizer, err := NewOptimizer(opt)
+	if err != nil {
+		fmt.Printf("Warning: %v, falling back to ftrl\n", err)
+		opt.Optimizer = OptimizerFTRL
+		optimizer, _ = NewOptimizer(opt)
+	}
+	t.optimizer = optimizer
+	// 初始化训练目标
+	loss, err := NewLoss(opt.Loss)
+	if err != nil {
+		fmt.Printf("Warning: %v, falling back to logistic\n", err)
+		opt.Loss = LossLogistic
+		loss, _ = NewLoss(opt.Loss)
+	}
+	t.loss = loss
+	t.labelKind = LabelKind(opt.Loss, opt.Rank != "" || opt.ClassNum > 0)
+	t.model.SetMeta(opt.ModelMeta())
+	t.enableHashStats()
+	if opt.Seed != 0 || opt.Deterministic {
+		t.model.SetInitSeed(opt.Seed)
+	}
+	if admission := opt.NewAdmission(); admission != nil {
+		t.model.SetAdmission(admission)
 	}
 	
 	// 初始化SIMD
@@ -102,80 +216,332 @@ func NewFTRLTrainer(opt *TrainerOption) *FTRLTrainer {
 
 // RunTask 处理一批数据
 func (t *FTRLTrainer) RunTask(dataBuffer []string) error {
+	samples := t.parseLines(dataBuffer)
+	if t.sampleCache != nil {
+		if err := t.sampleCache.AddBatch(samples); err != nil {
+			return err
+		}
+	}
+	return t.runSamples(samples, true)
+}
+
+// SkipTask 续训时跳过的行：只解析并写入样本缓存，不训练，使后续轮次回放完整的输入
+func (t *FTRLTrainer) SkipTask(dataBuffer []string) error {
+	if t.sampleCache == nil {
+		return nil
+	}
+	return t.sampleCache.AddBatch(t.parseLines(dataBuffer))
+}
+
+// parseLines 解析一批样本行，跳过不合法的样本
+func (t *FTRLTrainer) parseLines(dataBuffer []string) []*sample.FMSample {
+	isFFM := t.opt.ModelType == ModelTypeFFM
+	samples := make([]*sample.FMSample, 0, len(dataBuffer))
 	for _, line := range dataBuffer {
-		s, err := sample.ParseSample(line)
+		var s *sample.FMSample
+		var err error
+		var group string
+		if t.opt.Rank != "" {
+			if group, line, err = sample.SplitGroupColumn(line); err != nil {
+				fmt.Printf("Warning: skip invalid sample: %v\n", err)
+				continue
+			}
+		}
+		if isFFM {
+			s, err = sample.ParseFFMSample(line, t.opt.FieldNum, t.labelKind)
+		} else {
+			s, err = sample.ParseSample(line, t.labelKind)
+		}
 		if err != nil {
 			fmt.Printf("Warning: skip invalid sample: %v\n", err)
 			continue
 		}
-		t.train(s.Y, s.X)
+		if t.opt.ClassNum > 0 {
+			if _, err := ClassLabel(s.Label, t.opt.ClassNum); err != nil {
+				fmt.Printf("Warning: skip invalid sample: %v\n", err)
+				continue
+			}
+		}
+		s.Group = group
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+// RunSamples 训练一批已解析的样本（回放的样本已训练过，不计入渐进式验证）
+func (t *FTRLTrainer) RunSamples(batch []*sample.FMSample) error {
+	return t.runSamples(batch, false)
+}
+
+func (t *FTRLTrainer) runSamples(batch []*sample.FMSample, progressive bool) error {
+	if t.opt.Rank != "" {
+		return t.runRankSamples(batch, progressive)
+	}
+	if t.opt.ClassNum > 0 {
+		return t.runMulticlassSamples(batch, progressive)
+	}
+	isFFM := t.opt.ModelType == ModelTypeFFM
+	lossSum, weightSum := 0.0, 0.0
+	var probs, labels, weights []float64
+	if progressive {
+		probs = make([]float64, len(batch))
+		labels = make([]float64, len(batch))
+		weights = make([]float64, len(batch))
+	}
+	if t.opt.evictionEnabled() {
+		atomic.AddInt64(&t.seen, int64(len(batch)))
+	}
+	var ps []float64
+	if t.opt.Deterministic && !isFFM {
+		ps = t.trainSync(batch)
+	}
+	for i, s := range batch {
+		var p float64
+		switch {
+		case ps != nil:
+			p = ps[i]
+		case isFFM:
+			p = t.trainFFM(s.Label, s.W, s.X)
+		default:
+			p = t.train(s.Label, s.W, s.X)
+		}
+		lossSum += s.W * t.loss.Loss(p, s.Label)
+		weightSum += s.W
+		if progressive {
+			probs[i] = 1.0 / (1.0 + math.Exp(-p))
+			if s.Y > 0 {
+				labels[i] = 1.0
+			}
+			weights[i] = s.W
+		}
 	}
+
+	switch {
+	case !progressive:
+	case t.loss.Name() == LossLogistic:
+		t.progressive.AddBatch(probs, labels, weights)
+	default:
+		t.progressive.AddLoss(int64(len(batch)), lossSum, weightSum)
+	}
+	if t.val != nil {
+		atomic.AddInt64(&t.val.trained, int64(len(batch)))
+	}
+	t.lossMu.Lock()
+	t.lossSum += lossSum
+	t.lossWeight += weightSum
+	t.lossNum += int64(len(batch))
+	t.lossMu.Unlock()
 	return nil
 }
 
+// SetSampleCache 设置样本缓存，RunTask解析出的样本会写入缓存供后续轮次回放
+func (t *FTRLTrainer) SetSampleCache(cache *sample.SampleCache) {
+	t.sampleCache = cache
+}
+
+// TakeTrainLoss 返回上次调用以来按样本权重加权的平均训练损失和样本数，并清零
+func (t *FTRLTrainer) TakeTrainLoss() (float64, int64) {
+	t.lossMu.Lock()
+	defer t.lossMu.Unlock()
+	lossSum, lossWeight, lossNum := t.lossSum, t.lossWeight, t.lossNum
+	t.lossSum, t.lossWeight, t.lossNum = 0, 0, 0
+	if lossWeight == 0 {
+		return 0, lossNum
+	}
+	return lossSum / lossWeight, lossNum
+}
+
+// enableHashStats 按选项开启特征哈希冲突统计（加载模型会重建特征存储，需要重新开启）
+func (t *FTRLTrainer) enableHashStats() {
+	if hasher := t.model.Hasher(); hasher != nil && t.opt.HashStats {
+		hasher.EnableStats()
+	}
+}
+
+// BucketStoreBytes 限定哈希桶数时特征存储预分配的字节数，未使用桶数组时返回false
+func (t *FTRLTrainer) BucketStoreBytes() (int64, bool) {
+	if b := t.model.store.buckets; b != nil {
+		return b.bytes(), true
+	}
+	return 0, false
+}
+
+// HashStats 特征哈希冲突统计，未开启时返回false
+func (t *FTRLTrainer) HashStats() (HashStats, bool) {
+	if hasher := t.model.Hasher(); hasher != nil {
+		return hasher.Stats()
+	}
+	return HashStats{}, false
+}
+
+// FeatureNum 模型中的特征数
+func (t *FTRLTrainer) FeatureNum() int {
+	return t.model.FeatureNum()
+}
+
+// AdmissionRejected 因未通过准入被忽略的特征出现次数，未开启准入时返回false
+func (t *FTRLTrainer) AdmissionRejected() (int64, bool) {
+	if t.model.admission == nil {
+		return 0, false
+	}
+	return t.model.admission.Rejected(), true
+}
+
+// Progress 当前的渐进式验证指标，由PCFrame在输出进度时调用
+func (t *FTRLTrainer) Progress() string {
+	if t.opt.Rank != "" {
+		return fmt.Sprintf("progressive %s pairwise loss: %.6f", t.opt.Rank, t.progressive.Report().LogLoss)
+	}
+	if t.opt.ClassNum > 0 {
+		return fmt.Sprintf("progressive multiclass logloss: %.6f", t.progressive.Report().LogLoss)
+	}
+	if t.loss.Name() != LossLogistic {
+		return fmt.Sprintf("progressive %s loss: %.6f", t.loss.Name(), t.progressive.Report().LogLoss)
+	}
+	return t.progressive.Report().String()
+}
+
+// LossName 训练目标的名称
+func (t *FTRLTrainer) LossName() string {
+	return t.loss.Name()
+}
+
+// ProgressiveReport 返回首轮训练的渐进式验证结果
+func (t *FTRLTrainer) ProgressiveReport() *metrics.ProgressiveReport {
+	return t.progressive.Report()
+}
+
+// logLoss 由未经sigmoid的预测值计算logloss（y为1或-1）
+func logLoss(p float64, y int) float64 {
+	z := p * float64(y)
+	if z > 0 {
+		return math.Log1p(math.Exp(-z))
+	}
+	return -z + math.Log1p(math.Exp(z))
+}
+
 // LoadModel 加载模型
+// 初始模型的优化器必须与当前一致，否则n、z槽位的含义不同
 func (t *FTRLTrainer) LoadModel(modelPath, modelFormat string) error {
-	return t.model.LoadModel(modelPath, modelFormat)
+	if err := t.model.LoadModel(modelPath, modelFormat); err != nil {
+		return err
+	}
+	if err := t.opt.ModelMeta().CheckHashing(t.model.Meta); err != nil {
+		return err
+	}
+	t.enableHashStats()
+	if t.model.Meta.Optimizer != t.optimizer.Name() {
+		return fmt.Errorf("optimizer mismatch: model=%s, expected=%s", t.model.Meta.Optimizer, t.optimizer.Name())
+	}
+	if t.model.Meta.Loss != t.loss.Name() {
+		return fmt.Errorf("loss mismatch: model=%s, expected=%s", t.model.Meta.Loss, t.loss.Name())
+	}
+	if so, ok := t.optimizer.(stepOptimizer); ok {
+		so.SetSteps(t.model.Meta.OptimizerStep)
+	}
+	// 检查点的输入行数只对本次续训有意义，不再写入输出的模型
+	t.resumeLines = t.model.Meta.InputLines
+	t.model.Meta.InputLines = 0
+	// 指定了采样率时覆盖初始模型中记录的值
+	if t.opt.NegSampleRate > 0 {
+		t.model.Meta.NegSampleRate = t.opt.NegSampleRate
+	}
+	return nil
+}
+
+// HasBestModel 是否有验证指标最好的模型快照（加载了验证集且至少验证过一次）
+func (t *FTRLTrainer) HasBestModel() bool {
+	return t.val != nil && t.val.best != nil
 }
 
-// OutputModel 输出模型
+// OutputBestModel 输出验证指标最好的模型快照，返回快照的指标值
+func (t *FTRLTrainer) OutputBestModel(modelPath, modelFormat string) (float64, error) {
+	if !t.HasBestModel() {
+		return 0, fmt.Errorf("no validated model snapshot")
+	}
+	return t.val.bestScore, t.val.best.OutputModel(modelPath, modelFormat)
+}
+
+// OutputModel 输出当前的模型
 func (t *FTRLTrainer) OutputModel(modelPath, modelFormat string) error {
+	if so, ok := t.optimizer.(stepOptimizer); ok {
+		t.model.Meta.OptimizerStep = so.Steps()
+	}
 	return t.model.OutputModel(modelPath, modelFormat)
 }
 
-// train 训练一个样本
-func (t *FTRLTrainer) train(y int, x []sample.FeatureValue) {
-	thetaBias := t.model.GetOrInitModelUnitBias()
-	xLen := len(x)
-	theta := make([]*FTRLModelUnit, xLen)
-	feaLocks := make([]*sync.Mutex, xLen+1)
-
-	// 获取模型单元和锁
-	for i := 0; i < xLen; i++ {
-		theta[i] = t.model.GetOrInitModelUnit(x[i].Feature)
-		feaLocks[i] = t.lockPool.GetFeatureLock(x[i].Feature)
+// tick 每个训练样本开始时调用
+func (t *FTRLTrainer) tick() {
+	if so, ok := t.optimizer.(stepOptimizer); ok {
+		so.Tick()
 	}
-	feaLocks[xLen] = t.lockPool.GetBiasLock()
+}
 
-	// 更新w（FTRL）
-	for i := 0; i <= xLen; i++ {
-		var mu *FTRLModelUnit
-		if i < xLen {
-			mu = theta[i]
+// nopLock Hogwild模式下使用的空锁
+type nopLock struct{}
+
+func (nopLock) Lock()   {}
+func (nopLock) Unlock() {}
+
+// getUnitsAndLocks 获取样本特征对应的模型单元和锁（锁数组最后一个为bias锁）
+// 特征的锁为其所在存储分片的更新锁，Hogwild模式下全部为空锁；
+// 未通过准入的特征视为不存在，返回去掉这些特征后的样本
+func (t *FTRLTrainer) getUnitsAndLocks(x []sample.FeatureValue) ([]sample.FeatureValue, []*FTRLModelUnit, []sync.Locker) {
+	xLen := len(x)
+	theta := make([]*FTRLModelUnit, 0, xLen)
+	feaLocks := make([]sync.Locker, 0, xLen+1)
+	kept, filtered := x, false
+	stamp := t.stamp()
+	for i := 0; i < xLen; i++ {
+		var unit *FTRLModelUnit
+		var lock sync.Locker = nopLock{}
+		if t.opt.Hogwild {
+			unit = t.model.GetOrInitModelUnit(x[i].Feature)
 		} else {
-			mu = thetaBias
+			var mu *sync.Mutex
+			unit, mu = t.model.GetOrInitModelUnitWithLock(x[i].Feature)
+			lock = mu
 		}
-
-		if (i < xLen && t.opt.K1) || (i == xLen && t.opt.K0) {
-			feaLocks[i].Lock()
-			if math.Abs(mu.WZi) <= t.opt.WL1 {
-				mu.Wi = 0.0
-			} else {
-				if t.opt.ForceVSparse && mu.WNi > 0 && mu.Wi == 0.0 {
-					mu.ReinitVi(t.model.InitMean, t.model.InitStdev)
-				}
-				mu.Wi = -1.0 * (1.0 / (t.opt.WL2 + (t.opt.WBeta+math.Sqrt(mu.WNi))/t.opt.WAlpha)) *
-					(mu.WZi - float64(utils.Sgn(mu.WZi))*t.opt.WL1)
+		if unit == nil {
+			if !filtered {
+				kept = append(make([]sample.FeatureValue, 0, xLen), x[:i]...)
+				filtered = true
 			}
-			feaLocks[i].Unlock()
+			continue
+		}
+		if filtered {
+			kept = append(kept, x[i])
 		}
+		if stamp >= 0 {
+			unit.touch(stamp)
+		}
+		theta = append(theta, unit)
+		feaLocks = append(feaLocks, lock)
+	}
+	if t.opt.Hogwild {
+		feaLocks = append(feaLocks, nopLock{})
+	} else {
+		feaLocks = append(feaLocks, &t.biasLock)
 	}
+	return kept, theta, feaLocks
+}
+
+// train 训练一个样本，返回更新前的线性预测值（未经link函数），label为原始标签，weight为样本权重
+func (t *FTRLTrainer) train(label, weight float64, x []sample.FeatureValue) float64 {
+	thetaBias := t.model.GetOrInitModelUnitBias()
+	x, theta, feaLocks := t.getUnitsAndLocks(x)
+	xLen := len(x)
+	t.tick()
+
+	// 更新w
+	t.updateW(theta, thetaBias, feaLocks)
 
-	// 更新v（FTRL）
+	// 更新v
 	for i := 0; i < xLen; i++ {
 		mu := theta[i]
 		for f := 0; f < t.model.FactorNum; f++ {
 			feaLocks[i].Lock()
-			if mu.VNi[f] > 0 {
-				if t.opt.ForceVSparse && mu.Wi == 0.0 {
-					mu.Vi[f] = 0.0
-				} else if math.Abs(mu.VZi[f]) <= t.opt.VL1 {
-					mu.Vi[f] = 0.0
-				} else {
-					mu.Vi[f] = -1.0 * (1.0 / (t.opt.VL2 + (t.opt.VBeta+math.Sqrt(mu.VNi[f]))/t.opt.VAlpha)) *
-						(mu.VZi[f] - float64(utils.Sgn(mu.VZi[f]))*t.opt.VL1)
-				}
-			}
+			t.updateVi(mu, f)
 			feaLocks[i].Unlock()
 		}
 	}
@@ -202,10 +568,74 @@ func (t *FTRLTrainer) train(y int, x []sample.FeatureValue) {
 		}
 	}
 
-	// 计算梯度系数
-	mult := float64(y) * (1.0/(1.0+math.Exp(-p*float64(y))) - 1.0)
+	// 计算梯度系数，按样本权重缩放
+	mult := weight * t.loss.Gradient(p, label)
 
 	// 更新w_n, w_z
+	t.updateWGradients(theta, thetaBias, feaLocks, x, mult)
+
+	// 更新v_n, v_z（使用SIMD优化）
+	if t.useSIMD && xLen > 0 {
+		t.updateVGradientsSIMD(theta, feaLocks, x, sum, mult)
+	} else {
+		// 标量版本
+		for i := 0; i < xLen; i++ {
+			mu := theta[i]
+			xi := x[i].Value
+
+			for f := 0; f < t.model.FactorNum; f++ {
+				feaLocks[i].Lock()
+				vGif := mult * (sum[f]*xi - mu.Vi[f]*xi*xi)
+				t.updateViGradient(mu, f, vGif)
+				feaLocks[i].Unlock()
+			}
+		}
+	}
+
+	return p
+}
+
+// updateW 预测前计算bias和一阶权重w（FTRL由z、n惰性求解）
+func (t *FTRLTrainer) updateW(theta []*FTRLModelUnit, thetaBias *FTRLModelUnit, feaLocks []sync.Locker) {
+	xLen := len(theta)
+	for i := 0; i <= xLen; i++ {
+		var mu *FTRLModelUnit
+		if i < xLen {
+			mu = theta[i]
+		} else {
+			mu = thetaBias
+		}
+
+		if (i < xLen && t.opt.K1) || (i == xLen && t.opt.K0) {
+			feaLocks[i].Lock()
+			t.prepareW(mu)
+			feaLocks[i].Unlock()
+		}
+	}
+}
+
+// prepareW 预测前计算单元的w（调用方持有特征锁）
+func (t *FTRLTrainer) prepareW(mu *FTRLModelUnit) {
+	wasZero := mu.Wi == 0.0
+	t.optimizer.PrepareW(&mu.Wi, &mu.WNi, &mu.WZi)
+	// w由0变为非0时重新初始化被强制置0的v
+	if t.opt.ForceVSparse && mu.WNi > 0 && wasZero && mu.Wi != 0.0 {
+		t.model.ReinitVi(mu)
+	}
+}
+
+// updateVi 预测前计算隐向量第f维（调用方持有特征锁）
+func (t *FTRLTrainer) updateVi(mu *FTRLModelUnit, f int) {
+	t.optimizer.PrepareV(&mu.Vi[f], &mu.VNi[f], &mu.VZi[f])
+	if t.opt.ForceVSparse && mu.VNi[f] > 0 && mu.Wi == 0.0 {
+		mu.Vi[f] = 0.0
+	}
+}
+
+// updateWGradients 根据梯度系数更新bias和一阶项
+func (t *FTRLTrainer) updateWGradients(theta []*FTRLModelUnit, thetaBias *FTRLModelUnit, feaLocks []sync.Locker,
+	x []sample.FeatureValue, mult float64) {
+	xLen := len(x)
 	for i := 0; i <= xLen; i++ {
 		var mu *FTRLModelUnit
 		var xi float64
@@ -219,36 +649,18 @@ func (t *FTRLTrainer) train(y int, x []sample.FeatureValue) {
 
 		if (i < xLen && t.opt.K1) || (i == xLen && t.opt.K0) {
 			feaLocks[i].Lock()
-			wGi := mult * xi
-			wSi := (1.0 / t.opt.WAlpha) * (math.Sqrt(mu.WNi+wGi*wGi) - math.Sqrt(mu.WNi))
-			mu.WZi += wGi - wSi*mu.Wi
-			mu.WNi += wGi * wGi
+			t.optimizer.UpdateW(&mu.Wi, &mu.WNi, &mu.WZi, mult*xi)
 			feaLocks[i].Unlock()
 		}
 	}
+}
 
-	// 更新v_n, v_z（使用SIMD优化）
-	if t.useSIMD && xLen > 0 {
-		t.updateVGradientsSIMD(theta, feaLocks, x, sum, mult)
-	} else {
-		// 标量版本
-		for i := 0; i < xLen; i++ {
-			mu := theta[i]
-			xi := x[i].Value
-
-			for f := 0; f < t.model.FactorNum; f++ {
-				feaLocks[i].Lock()
-				vGif := mult * (sum[f]*xi - mu.Vi[f]*xi*xi)
-				vSif := (1.0 / t.opt.VAlpha) * (math.Sqrt(mu.VNi[f]+vGif*vGif) - math.Sqrt(mu.VNi[f]))
-				mu.VZi[f] += vGif - vSif*mu.Vi[f]
-				mu.VNi[f] += vGif * vGif
+// updateViGradient 根据梯度更新隐向量第f维（调用方持有特征锁）
+func (t *FTRLTrainer) updateViGradient(mu *FTRLModelUnit, f int, vGif float64) {
+	t.optimizer.UpdateV(&mu.Vi[f], &mu.VNi[f], &mu.VZi[f], vGif)
 
-				if t.opt.ForceVSparse && mu.VNi[f] > 0 && mu.Wi == 0.0 {
-					mu.Vi[f] = 0.0
-				}
-				feaLocks[i].Unlock()
-			}
-		}
+	if t.opt.ForceVSparse && mu.VNi[f] > 0 && mu.Wi == 0.0 {
+		mu.Vi[f] = 0.0
 	}
 }
 
@@ -321,15 +733,12 @@ func (t *FTRLTrainer) predictScalar(x []sample.FeatureValue, bias float64, theta
 }
 
 // updateVGradientsSIMD 使用SIMD更新v的梯度
-func (t *FTRLTrainer) updateVGradientsSIMD(theta []*FTRLModelUnit, feaLocks []*sync.Mutex, 
+func (t *FTRLTrainer) updateVGradientsSIMD(theta []*FTRLModelUnit, feaLocks []sync.Locker, 
 	x []sample.FeatureValue, sum []float64, mult float64) {
 	
 	xLen := len(x)
 	factorNum := t.model.FactorNum
 	
-	// 预计算常用值
-	invVAlpha := 1.0 / t.opt.VAlpha
-	
 	for i := 0; i < xLen; i++ {
 		mu := theta[i]
 		xi := x[i].Value
@@ -337,17 +746,10 @@ func (t *FTRLTrainer) updateVGradientsSIMD(theta []*FTRLModelUnit, feaLocks []*s
 
 		feaLocks[i].Lock()
 		
-		// 对于每个维度，使用向量化计算
+		// 对于每个维度，计算梯度并交给优化器更新
 		for f := 0; f < factorNum; f++ {
 			vGif := mult * (sum[f]*xi - mu.Vi[f]*xixi)
-			vGifSqr := vGif * vGif
-			vSif := invVAlpha * (math.Sqrt(mu.VNi[f]+vGifSqr) - math.Sqrt(mu.VNi[f]))
-			mu.VZi[f] += vGif - vSif*mu.Vi[f]
-			mu.VNi[f] += vGifSqr
-
-			if t.opt.ForceVSparse && mu.VNi[f] > 0 && mu.Wi == 0.0 {
-				mu.Vi[f] = 0.0
-			}
+			t.updateViGradient(mu, f, vGif)
 		}
 		
 		feaLocks[i].Unlock()
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDeterministicTraining(t *testing.T) {
+	lines := genFMLines(3000, rand.New(rand.NewSource(1)))
+	train := func(threads int) *FTRLTrainer {
+		opt := NewTrainerOption()
+		opt.FactorNum = 4
+		opt.ThreadsNum = threads
+		opt.Seed = 7
+		opt.Deterministic = true
+		opt.SyncBatch = 64
+		trainer := NewFTRLTrainer(opt)
+		if err := trainer.RunTask(lines); err != nil {
+			t.Fatal(err)
+		}
+		return trainer
+	}
+
+	// 不同线程数的模型逐位相同
+	a, b := train(1), train(4)
+	if a.model.MuBias.Wi != b.model.MuBias.Wi || a.model.MuBias.WNi != b.model.MuBias.WNi {
+		t.Fatalf("bias differs: %+v vs %+v", *a.model.MuBias, *b.model.MuBias)
+	}
+	if a.model.FeatureNum() != b.model.FeatureNum() {
+		t.Fatalf("feature num differs: %d vs %d", a.model.FeatureNum(), b.model.FeatureNum())
+	}
+	err := a.model.store.forEach(func(name string, ua *FTRLModelUnit) error {
+		ub, ok := b.model.GetModelUnit(name)
+		if !ok {
+			t.Fatalf("feature %s missing", name)
+		}
+		if ua.Wi != ub.Wi || ua.WNi != ub.WNi || ua.WZi != ub.WZi {
+			t.Fatalf("feature %s: w differs", name)
+		}
+		for f := range ua.Vi {
+			if ua.Vi[f] != ub.Vi[f] || ua.VNi[f] != ub.VNi[f] || ua.VZi[f] != ub.VZi[f] {
+				t.Fatalf("feature %s: v[%d] differs", name, f)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos, _ := a.model.GetModelUnit("pos")
+	if pos.Wi <= 0 {
+		t.Fatalf("pos weight = %v, want positive", pos.Wi)
+	}
+}
+
+func TestSeededInit(t *testing.T) {
+	newModel := func(seed int64) *FTRLModel {
+		m := NewFTRLModel(4, 0, 0.1)
+		m.SetInitSeed(seed)
+		return m
+	}
+	a, b, c := newModel(1), newModel(1), newModel(2)
+	// 初始值只取决于种子和特征名，与创建顺序无关
+	a.GetOrInitModelUnit("x")
+	ua := a.GetOrInitModelUnit("y")
+	ub := b.GetOrInitModelUnit("y")
+	uc := c.GetOrInitModelUnit("y")
+	for f := range ua.Vi {
+		if ua.Vi[f] != ub.Vi[f] {
+			t.Fatalf("v[%d] differs with the same seed", f)
+		}
+	}
+	if ua.Vi[0] == uc.Vi[0] {
+		t.Fatal("different seeds should give different init")
+	}
+}
+
+func TestSampleWeight(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask([]string{"1:0 a:1"}); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := trainer.model.GetModelUnit("a")
+	if a.WNi != 0 || a.WZi != 0 || trainer.model.MuBias.WNi != 0 {
+		t.Fatalf("zero weight sample should not update the model: %+v", *a)
+	}
+
+	if err := trainer.RunTask([]string{"1:3 b:1", "1 c:1"}); err != nil {
+		t.Fatal(err)
+	}
+	// 更新前模型为空，预测值为0，梯度为权重*(sigmoid(0)-1)，n为梯度平方
+	b, _ := trainer.model.GetModelUnit("b")
+	if math.Abs(b.WNi-2.25) > 1e-12 {
+		t.Fatalf("weighted gradient: n = %v, want 2.25", b.WNi)
+	}
+	loss, num := trainer.TakeTrainLoss()
+	if num != 3 || loss <= 0 {
+		t.Fatalf("train loss = %v, num = %d", loss, num)
+	}
+}
@@ -0,0 +1,115 @@
+//go:build !race
+// +build !race
+
+// Hogwild训练按设计存在无锁的数据竞争，race检测下不运行
+
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHogwildLearns(t *testing.T) {
+	rand.Seed(1)
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.Hogwild = true
+	trainer := NewFTRLTrainer(opt)
+
+	// 多个线程同时无锁更新同样的特征
+	var wg sync.WaitGroup
+	for th := 0; th < 4; th++ {
+		lines := genFMLines(2000, rand.New(rand.NewSource(int64(th))))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := trainer.RunTask(lines); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	pos, ok := trainer.model.GetModelUnit("pos")
+	if !ok {
+		t.Fatal("feature pos not trained")
+	}
+	neg, ok := trainer.model.GetModelUnit("neg")
+	if !ok {
+		t.Fatal("feature neg not trained")
+	}
+	if pos.Wi <= 0 || neg.Wi >= 0 {
+		t.Fatalf("unexpected weights: pos=%v neg=%v", pos.Wi, neg.Wi)
+	}
+	if trainer.model.FeatureNum() != 7 {
+		t.Fatalf("feature num = %d, want 7", trainer.model.FeatureNum())
+	}
+}
+
+// genSkewedLines 生成特征频率偏斜的样本，少数高频特征被多个线程同时更新
+func genSkewedLines(n int, r *rand.Rand) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		var sb strings.Builder
+		sb.WriteString(strconv.Itoa(r.Intn(2)))
+		for j := 0; j < 20; j++ {
+			fmt.Fprintf(&sb, " f%d:1", r.Intn(r.Intn(10000)+1))
+		}
+		lines[i] = sb.String()
+	}
+	return lines
+}
+
+// BenchmarkTrainHogwild 对比加锁和Hogwild模式下多线程训练的吞吐，每次迭代训练一批1000个样本
+//
+//	go test -run '^$' -bench TrainHogwild ./pkg/model
+func BenchmarkTrainHogwild(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	batches := make([][]string, 64)
+	for i := range batches {
+		batches[i] = genSkewedLines(1000, r)
+	}
+
+	for _, hogwild := range []bool{false, true} {
+		for _, threads := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("hogwild=%v/threads=%d", hogwild, threads), func(b *testing.B) {
+				opt := NewTrainerOption()
+				opt.FactorNum = 8
+				opt.Seed = 1
+				opt.Hogwild = hogwild
+				trainer := NewFTRLTrainer(opt)
+				// 先训练一遍使特征都已创建，只测量更新参数的开销
+				for _, batch := range batches {
+					trainer.RunTask(batch)
+				}
+				var next int64
+				var wg sync.WaitGroup
+				b.ResetTimer()
+				start := time.Now()
+				for th := 0; th < threads; th++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							i := atomic.AddInt64(&next, 1) - 1
+							if i >= int64(b.N) {
+								return
+							}
+							trainer.RunTask(batches[i%int64(len(batches))])
+						}
+					}()
+				}
+				wg.Wait()
+				b.ReportMetric(float64(b.N)*1000/time.Since(start).Seconds(), "samples/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"math"
+
+	"github.com/xiongle/alphaFM-go/pkg/utils"
+)
+
+// initRNG 按特征初始化隐向量使用的随机源（splitmix64）
+// 种子由全局种子和特征哈希导出，同一特征的初始值与训练线程数和特征出现顺序无关
+type initRNG struct {
+	state uint64
+}
+
+func newInitRNG(seed, id uint64) *initRNG {
+	return &initRNG{state: seed*0x9E3779B97F4A7C15 ^ id}
+}
+
+func (r *initRNG) next() uint64 {
+	r.state += 0x9E3779B97F4A7C15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// Float64 [0,1)均匀分布
+func (r *initRNG) Float64() float64 {
+	return float64(r.next()>>11) / (1 << 53)
+}
+
+// fill 用正态分布随机数填充隐向量
+func (r *initRNG) fill(v []float64, mean, stdev float64) {
+	for f := range v {
+		if stdev == 0.0 {
+			v[f] = mean
+		} else {
+			v[f] = mean + stdev*utils.GaussianFrom(r.Float64)
+		}
+	}
+}
+
+// SetInitSeed 开启按特征的确定性初始化：新特征的隐向量由seed和特征名（特征哈希模式下为键）决定
+func (m *FTRLModel) SetInitSeed(seed int64) {
+	m.initSeed = uint64(seed)
+	m.seeded = true
+}
+
+// ReinitVi 重新初始化单元的隐向量（-fvs模式下w由0变为非0时调用）
+// 开启确定性初始化时随机源由单元当前的优化器状态导出，相同的训练过程得到相同的结果
+func (m *FTRLModel) ReinitVi(u *FTRLModelUnit) {
+	if !m.seeded {
+		u.ReinitVi(m.InitMean, m.InitStdev)
+		return
+	}
+	id := math.Float64bits(u.WNi) ^ math.Float64bits(u.WZi)<<1
+	newInitRNG(m.initSeed, id).fill(u.Vi, m.InitMean, m.InitStdev)
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// 损失函数类型
+const (
+	LossLogistic = "logistic"
+	LossSquared  = "squared"
+	LossPoisson  = "poisson"
+)
+
+// poissonMaxMargin Poisson目标的线性预测值上限，避免exp溢出
+const poissonMaxMargin = 30.0
+
+// Loss 训练目标
+// p为模型的线性预测值（bias+一阶项+二阶项），label为样本的原始标签
+type Loss interface {
+	// Name 损失函数名称
+	Name() string
+	// Loss 单个样本的损失
+	Loss(p, label float64) float64
+	// Gradient 损失对线性预测值的导数
+	Gradient(p, label float64) float64
+	// Link 由线性预测值得到预测结果（link函数的反函数）
+	Link(p float64) float64
+}
+
+// IsValidLoss 判断损失函数名称是否合法
+func IsValidLoss(name string) bool {
+	switch name {
+	case LossLogistic, LossSquared, LossPoisson:
+		return true
+	}
+	return false
+}
+
+// NewLoss 根据名称创建损失函数
+func NewLoss(name string) (Loss, error) {
+	switch name {
+	case LossLogistic, "":
+		return logisticLoss{}, nil
+	case LossSquared:
+		return squaredLoss{}, nil
+	case LossPoisson:
+		return poissonLoss{}, nil
+	}
+	return nil, fmt.Errorf("unknown loss: %s (available: logistic, squared, poisson)", name)
+}
+
+// mustLoss 创建已校验过名称的损失函数
+func mustLoss(name string) Loss {
+	loss, err := NewLoss(name)
+	if err != nil {
+		panic(err)
+	}
+	return loss
+}
+
+// LabelKind 训练目标要求的标签取值范围
+// logistic目标只接受整数标签：二分类为0/1或-1/1，graded为true时（多分类的类别号、排序的相关度等级）接受任意整数
+func LabelKind(loss string, graded bool) sample.LabelKind {
+	switch loss {
+	case LossSquared:
+		return sample.LabelReal
+	case LossPoisson:
+		return sample.LabelNonNegative
+	}
+	if graded {
+		return sample.LabelInteger
+	}
+	return sample.LabelBinary
+}
+
+// logisticLoss 二分类logloss，标签大于0为正样本，其余为负样本
+type logisticLoss struct{}
+
+func (logisticLoss) Name() string { return LossLogistic }
+
+func (logisticLoss) Loss(p, label float64) float64 {
+	return logLoss(p, binaryY(label))
+}
+
+func (logisticLoss) Gradient(p, label float64) float64 {
+	y := float64(binaryY(label))
+	return y * (1.0/(1.0+math.Exp(-p*y)) - 1.0)
+}
+
+func (logisticLoss) Link(p float64) float64 {
+	return 1.0 / (1.0 + math.Exp(-p))
+}
+
+// binaryY 把原始标签转换为1/-1
+func binaryY(label float64) int {
+	if label > 0 {
+		return 1
+	}
+	return -1
+}
+
+// squaredLoss 平方损失，用于实数目标的回归，损失为(p-label)²/2
+type squaredLoss struct{}
+
+func (squaredLoss) Name() string { return LossSquared }
+
+func (squaredLoss) Loss(p, label float64) float64 {
+	return 0.5 * (p - label) * (p - label)
+}
+
+func (squaredLoss) Gradient(p, label float64) float64 {
+	return p - label
+}
+
+func (squaredLoss) Link(p float64) float64 {
+	return p
+}
+
+// poissonLoss Poisson回归，用于非负计数目标，log link
+// 损失为负对数似然exp(p)-label*p（省略与参数无关的log(label!)）
+type poissonLoss struct{}
+
+func (poissonLoss) Name() string { return LossPoisson }
+
+func (poissonLoss) Loss(p, label float64) float64 {
+	p = math.Min(p, poissonMaxMargin)
+	return math.Exp(p) - label*p
+}
+
+func (poissonLoss) Gradient(p, label float64) float64 {
+	return math.Exp(math.Min(p, poissonMaxMargin)) - label
+}
+
+func (poissonLoss) Link(p float64) float64 {
+	return math.Exp(math.Min(p, poissonMaxMargin))
+}
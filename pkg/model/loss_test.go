@@ -0,0 +1,77 @@
+package model
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestLossGradient(t *testing.T) {
+	const h = 1e-6
+	for _, name := range []string{LossLogistic, LossSquared, LossPoisson} {
+		loss, err := NewLoss(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, label := range []float64{0, 1, 2.5} {
+			for _, p := range []float64{-1.5, 0, 0.7} {
+				numeric := (loss.Loss(p+h, label) - loss.Loss(p-h, label)) / (2 * h)
+				if g := loss.Gradient(p, label); math.Abs(g-numeric) > 1e-5 {
+					t.Errorf("%s: gradient(%v, %v) = %v, numeric %v", name, p, label, g, numeric)
+				}
+			}
+		}
+	}
+	if _, err := NewLoss("hinge"); err == nil {
+		t.Fatal("unknown loss should be rejected")
+	}
+}
+
+func TestRegressionLosses(t *testing.T) {
+	// a的目标均值为3，b为5
+	var lines []string
+	for i := 0; i < 2000; i++ {
+		lines = append(lines, "2 a:1", "4 a:1", "5 b:1")
+	}
+	want := map[string]float64{"a": 3, "b": 5}
+
+	for _, name := range []string{LossSquared, LossPoisson} {
+		opt := NewTrainerOption()
+		opt.FactorNum = 2
+		opt.Loss = name
+		opt.WL1, opt.VL1 = 0, 0
+		opt.WL2, opt.VL2 = 0, 0
+		opt.WAlpha = 0.5
+		opt.ThreadsNum = 1
+		trainer := NewFTRLTrainer(opt)
+		if err := trainer.RunTask(lines); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(t.TempDir(), "model.txt")
+		if err := trainer.OutputModel(path, "txt"); err != nil {
+			t.Fatal(err)
+		}
+		pm := NewPredictModel(2)
+		if err := pm.LoadModel(path, "txt"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if pm.Meta.Loss != name {
+			t.Fatalf("%s: loss not recorded in meta: %s", name, pm.Meta.Loss)
+		}
+		for feature, target := range want {
+			x := []struct {
+				Feature string
+				Value   float64
+			}{{feature, 1}}
+			if p := pm.GetScore(x, pm.MuBias.Wi); math.Abs(p-target) > 0.3 {
+				t.Errorf("%s: prediction of %s = %v, want about %v", name, feature, p, target)
+			}
+		}
+
+		// 初始模型的训练目标必须一致
+		if err := NewFTRLTrainer(NewTrainerOption()).LoadModel(path, "txt"); err == nil {
+			t.Errorf("%s: loading with the logistic loss should fail", name)
+		}
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
-const modelVersion = 1
+const (
+	modelVersion     = 1 // 与C++版本alphaFM兼容的格式
+	modelVersionMeta = 2 // 头部之后带元信息块的格式（非默认模型）
+)
 
 // ModelBinInfo 二进制模型信息
 type ModelBinInfo struct {
@@ -26,12 +30,14 @@ type ModelBinFile struct {
 	file    *os.File
 	isRead  bool
 	version uint64
+	meta    ModelMeta
 }
 
 // NewModelBinFile 创建二进制模型文件处理器
 func NewModelBinFile() *ModelBinFile {
 	return &ModelBinFile{
 		version: modelVersion,
+		meta:    NewModelMeta(),
 	}
 }
 
@@ -48,7 +54,7 @@ func (m *ModelBinFile) OpenForRead(filePath string) error {
 	if err := binary.Read(f, binary.LittleEndian, &m.version); err != nil {
 		return err
 	}
-	if m.version != modelVersion {
+	if m.version != modelVersion && m.version != modelVersionMeta {
 		return fmt.Errorf("unsupported model version: %d", m.version)
 	}
 
@@ -60,11 +66,25 @@ func (m *ModelBinFile) OpenForRead(filePath string) error {
 		return fmt.Errorf("model file incomplete")
 	}
 
+	// 读取元信息
+	if m.version == modelVersionMeta {
+		meta, err := readMetaBlock(f)
+		if err != nil {
+			return err
+		}
+		m.meta = meta
+	}
+
 	return nil
 }
 
 // OpenForWrite 打开文件用于写入
 func (m *ModelBinFile) OpenForWrite(filePath string, numByteLen, factorNum, unitLen uint64) error {
+	return m.OpenForWriteWithMeta(filePath, numByteLen, factorNum, unitLen, NewModelMeta())
+}
+
+// OpenForWriteWithMeta 打开文件用于写入，非默认元信息使用version 2格式
+func (m *ModelBinFile) OpenForWriteWithMeta(filePath string, numByteLen, factorNum, unitLen uint64, meta ModelMeta) error {
 	f, err := os.Create(filePath)
 	if err != nil {
 		return err
@@ -77,6 +97,10 @@ func (m *ModelBinFile) OpenForWrite(filePath string, numByteLen, factorNum, unit
 		FactorNum:  factorNum,
 		UnitLen:    unitLen,
 	}
+	m.meta = meta
+	if !meta.IsDefault() {
+		m.version = modelVersionMeta
+	}
 
 	// 写入版本号和模型信息
 	if err := binary.Write(f, binary.LittleEndian, m.version); err != nil {
@@ -85,10 +109,46 @@ func (m *ModelBinFile) OpenForWrite(filePath string, numByteLen, factorNum, unit
 	if err := binary.Write(f, binary.LittleEndian, &m.info); err != nil {
 		return err
 	}
+	if m.version == modelVersionMeta {
+		if err := writeMetaBlock(f, meta); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// readMetaBlock 读取元信息块: meta_len(uint32) + meta(bytes)
+func readMetaBlock(r io.Reader) (ModelMeta, error) {
+	var metaLen uint32
+	if err := binary.Read(r, binary.LittleEndian, &metaLen); err != nil {
+		return ModelMeta{}, err
+	}
+	metaBytes := make([]byte, metaLen)
+	if _, err := io.ReadFull(r, metaBytes); err != nil {
+		return ModelMeta{}, err
+	}
+	return ParseModelMeta(string(metaBytes))
+}
+
+// writeMetaBlock 写入元信息块
+func writeMetaBlock(w io.Writer, meta ModelMeta) error {
+	metaStr := meta.String()
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(metaStr))); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(metaStr))
+	return err
+}
+
+// vecLen 由unit_len推算每个单元的向量长度（FFM时大于factor_num）
+func (m *ModelBinFile) vecLen() int {
+	if m.info.NumByteLen == 0 {
+		return int(m.info.FactorNum)
+	}
+	return int((m.info.UnitLen/m.info.NumByteLen - 3) / 3)
+}
+
 // ReadOneFea 读取一个特征名
 func (m *ModelBinFile) ReadOneFea() (string, error) {
 	var feaLen uint16
@@ -139,8 +199,8 @@ func (m *ModelBinFile) ReadOneUnitDouble(unit *FTRLModelUnit, factorNum int) err
 		}
 	}
 	
-	// 如果factorNum < m.info.FactorNum，跳过padding
-	expectedFactorNum := int(m.info.FactorNum)
+	// 如果factorNum小于单元的向量长度，跳过padding
+	expectedFactorNum := m.vecLen()
 	if factorNum < expectedFactorNum {
 		paddingCount := (expectedFactorNum - factorNum) * 3 * 8 // 字节数
 		padding := make([]byte, paddingCount)
@@ -194,8 +254,8 @@ func (m *ModelBinFile) ReadOneUnitFloat(unit *FTRLModelUnit, factorNum int) erro
 		unit.VZi[f] = float64(vz)
 	}
 	
-	// 如果factorNum < m.info.FactorNum，跳过padding
-	expectedFactorNum := int(m.info.FactorNum)
+	// 如果factorNum小于单元的向量长度，跳过padding
+	expectedFactorNum := m.vecLen()
 	if factorNum < expectedFactorNum {
 		paddingCount := (expectedFactorNum - factorNum) * 3 * 4 // float32字节数
 		padding := make([]byte, paddingCount)
@@ -269,8 +329,8 @@ func (m *ModelBinFile) WriteOneFeaUnitDouble(feaName string, unit *FTRLModelUnit
 		}
 	}
 
-	// 如果factorNum < m.info.FactorNum (如bias)，填充0使其达到unit_len
-	expectedFactorNum := int(m.info.FactorNum)
+	// 如果factorNum小于单元的向量长度 (如bias)，填充0使其达到unit_len
+	expectedFactorNum := m.vecLen()
 	if factorNum < expectedFactorNum {
 		paddingCount := (expectedFactorNum - factorNum) * 3 // vi, v_ni, v_zi
 		padding := make([]byte, paddingCount*8)
@@ -326,8 +386,8 @@ func (m *ModelBinFile) WriteOneFeaUnitFloat(feaName string, unit *FTRLModelUnit,
 		}
 	}
 
-	// 如果factorNum < m.info.FactorNum，填充0
-	expectedFactorNum := int(m.info.FactorNum)
+	// 如果factorNum小于单元的向量长度，填充0
+	expectedFactorNum := m.vecLen()
 	if factorNum < expectedFactorNum {
 		paddingCount := (expectedFactorNum - factorNum) * 3 // vi, v_ni, v_zi
 		padding := make([]byte, paddingCount*4) // float32
@@ -346,17 +406,30 @@ func (m *ModelBinFile) WriteOneFeaUnitFloat(feaName string, unit *FTRLModelUnit,
 
 // Close 关闭文件
 func (m *ModelBinFile) Close() error {
+	return m.close(false)
+}
+
+// CloseSync 与Close相同，写模式下在关闭前把数据和成功标志刷到磁盘
+func (m *ModelBinFile) CloseSync() error {
+	return m.close(true)
+}
+
+func (m *ModelBinFile) close(sync bool) error {
+	var err error
 	if !m.isRead {
 		// 写模式：更新成功标志
 		m.info.SuccessFlag = 1
-		if _, err := m.file.Seek(int64(binary.Size(m.version)), 0); err != nil {
-			return err
+		if _, err = m.file.Seek(int64(binary.Size(m.version)), 0); err == nil {
+			err = binary.Write(m.file, binary.LittleEndian, &m.info)
 		}
-		if err := binary.Write(m.file, binary.LittleEndian, &m.info); err != nil {
-			return err
+		if err == nil && sync {
+			err = m.file.Sync()
 		}
 	}
-	return m.file.Close()
+	if closeErr := m.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
 // GetInfo 获取模型信息
@@ -364,6 +437,11 @@ func (m *ModelBinFile) GetInfo() ModelBinInfo {
 	return m.info
 }
 
+// GetMeta 获取模型元信息
+func (m *ModelBinFile) GetMeta() ModelMeta {
+	return m.meta
+}
+
 // PrintInfo 打印模型信息
 func (m *ModelBinFile) PrintInfo() {
 	fmt.Printf("format_version: %d\n", m.version)
@@ -378,6 +456,9 @@ func (m *ModelBinFile) PrintInfo() {
 	fmt.Printf("feature_num: %d\n", m.info.FeaNum)
 	fmt.Printf("nonzero_feature_num: %d\n", m.info.NonzeroFeaNum)
 	fmt.Printf("success_flag: %v\n", m.info.SuccessFlag == 1)
+	if !m.meta.IsDefault() {
+		fmt.Printf("meta: %s\n", m.meta.String())
+	}
 }
 
 // ReadInfo 只读取模型信息
@@ -392,7 +473,7 @@ func ReadInfo(filePath string) (*ModelBinInfo, error) {
 	if err := binary.Read(f, binary.LittleEndian, &version); err != nil {
 		return nil, err
 	}
-	if version != modelVersion {
+	if version != modelVersion && version != modelVersionMeta {
 		return nil, fmt.Errorf("unsupported model version: %d", version)
 	}
 
@@ -408,6 +489,7 @@ func ReadInfo(filePath string) (*ModelBinInfo, error) {
 }
 
 // ConvertTxtToBin 文本模型转二进制
+// 按文本文件中的顺序逐行写入，bias行必须位于首行（元信息行之后）
 func ConvertTxtToBin(txtPath, binPath string, factorNum int, useFloat32 bool) error {
 	txtFile, err := os.Open(txtPath)
 	if err != nil {
@@ -422,28 +504,143 @@ func ConvertTxtToBin(txtPath, binPath string, factorNum int, useFloat32 bool) er
 		numByteLen = 8
 	}
 
-	// 计算unit长度
-	var unitLen uint64
-	if useFloat32 {
-		unitLen = uint64(3*4 + 3*factorNum*4) // wi, w_ni, w_zi + vi, v_ni, v_zi
-	} else {
-		unitLen = uint64(3*8 + 3*factorNum*8)
+	scanner := bufio.NewScanner(txtFile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	// 读取bias行，其前可能有元信息行
+	meta := NewModelMeta()
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("empty model file")
 	}
+	if isMetaLine(scanner.Text()) {
+		if meta, err = parseMetaLine(scanner.Text()); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			return fmt.Errorf("missing bias line")
+		}
+	}
+	biasLen := meta.BiasVecLen()
+	parts := strings.Fields(scanner.Text())
+	if len(parts) != 3*biasLen+4 || parts[0] != BiasFeatureName {
+		return fmt.Errorf("invalid bias line")
+	}
+	bias, err := NewFTRLModelUnitFromLine(biasLen, parts)
+	if err != nil {
+		return fmt.Errorf("invalid bias line: %v", err)
+	}
+	vecLen := meta.VecLen(factorNum)
+
+	// 计算unit长度: wi, w_ni, w_zi + vi, v_ni, v_zi
+	unitLen := numByteLen * uint64(3+3*vecLen)
 
 	mbf := NewModelBinFile()
-	if err := mbf.OpenForWrite(binPath, numByteLen, uint64(factorNum), unitLen); err != nil {
+	if err := mbf.OpenForWriteWithMeta(binPath, numByteLen, uint64(factorNum), unitLen, meta); err != nil {
 		return err
 	}
-	defer mbf.Close()
 
-	scanner := bufio.NewScanner(txtFile)
+	writeUnit := func(feaName string, unit *FTRLModelUnit, k int) error {
+		if useFloat32 {
+			return mbf.WriteOneFeaUnitFloat(feaName, unit, k, unit.IsNonZero())
+		}
+		return mbf.WriteOneFeaUnitDouble(feaName, unit, k, unit.IsNonZero())
+	}
+
+	if err := writeUnit(BiasFeatureName, bias, biasLen); err != nil {
+		mbf.file.Close()
+		return err
+	}
 
+	lineNum := 1
 	for scanner.Scan() {
-		_ = scanner.Text()
-		// 这里简化处理，实际需要解析每一行并写入
-		// 完整实现需要根据float32/float64类型解析和写入
+		lineNum++
+		parts := strings.Fields(scanner.Text())
+		unit, err := NewFTRLModelUnitFromLine(vecLen, parts)
+		if err != nil {
+			mbf.file.Close()
+			return fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		if err := writeUnit(parts[0], unit, vecLen); err != nil {
+			mbf.file.Close()
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		mbf.file.Close()
+		return err
 	}
 
-	return scanner.Err()
+	// 只有完整写入后才通过Close设置success_flag
+	return mbf.Close()
 }
 
+// ConvertBinToTxt 二进制模型转文本
+// 输出格式与FTRLModel.OutputModel(..., "txt")一致，onlyNonZero为true时跳过全零特征（bias始终输出）
+func ConvertBinToTxt(binPath string, w io.Writer, onlyNonZero bool) error {
+	mbf := NewModelBinFile()
+	if err := mbf.OpenForRead(binPath); err != nil {
+		return err
+	}
+	defer mbf.Close()
+
+	info := mbf.GetInfo()
+	meta := mbf.GetMeta()
+	vecLen := meta.VecLen(int(info.FactorNum))
+
+	readUnit := func(unit *FTRLModelUnit, k int) error {
+		switch info.NumByteLen {
+		case 8:
+			return mbf.ReadOneUnitDouble(unit, k)
+		case 4:
+			return mbf.ReadOneUnitFloat(unit, k)
+		}
+		return fmt.Errorf("unsupported number_byte_len: %d", info.NumByteLen)
+	}
+
+	writer := bufio.NewWriter(w)
+	if !meta.IsDefault() {
+		fmt.Fprintln(writer, metaLine(meta))
+	}
+
+	// 读取bias
+	feaName, err := mbf.ReadOneFea()
+	if err != nil {
+		return fmt.Errorf("failed to read bias feature name: %v", err)
+	}
+	if feaName != BiasFeatureName {
+		return fmt.Errorf("expected bias, got %s", feaName)
+	}
+	bias := newFTRLModelUnitVectors(meta.BiasVecLen())
+	if err := readUnit(bias, meta.BiasVecLen()); err != nil {
+		return fmt.Errorf("failed to read bias unit: %v", err)
+	}
+	fmt.Fprintf(writer, "%s %s\n", BiasFeatureName, bias.String())
+
+	// 逐个读取特征，复用同一个unit避免重复分配
+	unit := &FTRLModelUnit{
+		Vi:  make([]float64, vecLen),
+		VNi: make([]float64, vecLen),
+		VZi: make([]float64, vecLen),
+	}
+	for {
+		feaName, err := mbf.ReadOneFea()
+		if err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+			return fmt.Errorf("failed to read feature name: %v", err)
+		}
+		if err := readUnit(unit, vecLen); err != nil {
+			return fmt.Errorf("failed to read unit for %s: %v", feaName, err)
+		}
+		if onlyNonZero && !unit.IsNonZero() {
+			continue
+		}
+		fmt.Fprintf(writer, "%s %s\n", feaName, unit.String())
+	}
+
+	return writer.Flush()
+}
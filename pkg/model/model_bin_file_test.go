@@ -0,0 +1,123 @@
+package model
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTxtModel = `bias 0.00857715 2.4919 -0.0815555
+f5 -0.0357813 0.00377966 0 0 0 0.627287 1.86352 0.0037937 0.00045363 0.00783523 2.33367e-05 -0.199147 0.00544427 0.0382112 -0.000691865
+f8 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+f9 -0.0125919 0 0 -0.000981241 0 0.523699 0.3539 0.000264866 9.19241e-05 0.00229174 2.82008e-05 0.0479322 -0.00258542 0.125415 -0.0129339
+`
+
+func TestBinTxtRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	txtPath := filepath.Join(dir, "model.txt")
+	if err := os.WriteFile(txtPath, []byte(testTxtModel), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, useFloat32 := range []bool{false, true} {
+		binPath := filepath.Join(dir, "model.bin")
+		if err := ConvertTxtToBin(txtPath, binPath, 4, useFloat32); err != nil {
+			t.Fatalf("ConvertTxtToBin(float32=%v) failed: %v", useFloat32, err)
+		}
+
+		info, err := ReadInfo(binPath)
+		if err != nil {
+			t.Fatalf("ReadInfo failed: %v", err)
+		}
+		if info.FeaNum != 4 || info.NonzeroFeaNum != 3 {
+			t.Errorf("unexpected info: fea_num=%d nonzero_fea_num=%d", info.FeaNum, info.NonzeroFeaNum)
+		}
+
+		var buf bytes.Buffer
+		if err := ConvertBinToTxt(binPath, &buf, false); err != nil {
+			t.Fatalf("ConvertBinToTxt failed: %v", err)
+		}
+		if buf.String() != testTxtModel {
+			t.Errorf("round trip mismatch (float32=%v):\n%s", useFloat32, buf.String())
+		}
+
+		buf.Reset()
+		if err := ConvertBinToTxt(binPath, &buf, true); err != nil {
+			t.Fatalf("ConvertBinToTxt(onlyNonZero) failed: %v", err)
+		}
+		if strings.Contains(buf.String(), "f8 ") || strings.Count(buf.String(), "\n") != 3 {
+			t.Errorf("zero feature not dropped:\n%s", buf.String())
+		}
+	}
+}
+
+func TestBinModelLoadsInPredictModel(t *testing.T) {
+	dir := t.TempDir()
+	txtPath := filepath.Join(dir, "model.txt")
+	binPath := filepath.Join(dir, "model.bin")
+	if err := os.WriteFile(txtPath, []byte(testTxtModel), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertTxtToBin(txtPath, binPath, 4, false); err != nil {
+		t.Fatal(err)
+	}
+
+	txtModel := NewPredictModel(4)
+	if err := txtModel.LoadModel(txtPath, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	binModel := NewPredictModel(4)
+	if err := binModel.LoadModel(binPath, "bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	x := []struct {
+		Feature string
+		Value   float64
+	}{{"f5", 1}, {"f8", 1}, {"f9", 0.5}}
+	p1 := txtModel.GetScore(x, txtModel.MuBias.Wi)
+	p2 := binModel.GetScore(x, binModel.MuBias.Wi)
+	if p1 != p2 {
+		t.Errorf("score mismatch: txt=%v bin=%v", p1, p2)
+	}
+}
+
+func TestNegSampleRateMeta(t *testing.T) {
+	dir := t.TempDir()
+	txtPath := filepath.Join(dir, "model.txt")
+	binPath := filepath.Join(dir, "model.bin")
+	if err := os.WriteFile(txtPath, []byte("#meta model_type=fm neg_sample_rate=0.1\n"+testTxtModel), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConvertTxtToBin(txtPath, binPath, 4, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"txt", "bin"} {
+		path := txtPath
+		if format == "bin" {
+			path = binPath
+		}
+		m := NewPredictModel(4)
+		if err := m.LoadModel(path, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if m.Meta.NegSampleRate != 0.1 {
+			t.Fatalf("%s: neg_sample_rate = %v, want 0.1", format, m.Meta.NegSampleRate)
+		}
+		// 负样本保留10%时，未校准的0.5对应真实概率1/11
+		if q := m.Calibrate(0.5); math.Abs(q-1.0/11) > 1e-12 {
+			t.Fatalf("%s: calibrated 0.5 = %v, want %v", format, q, 1.0/11)
+		}
+	}
+
+	if p := CorrectNegSampling(0.3, 1); p != 0.3 {
+		t.Fatalf("rate 1 should not correct: %v", p)
+	}
+	if _, err := ParseModelMeta("model_type=fm neg_sample_rate=1.5"); err == nil {
+		t.Fatal("neg_sample_rate > 1 should be rejected")
+	}
+}
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 模型类型
+const (
+	ModelTypeFM  = "fm"
+	ModelTypeFFM = "ffm"
+)
+
+// metaLinePrefix 文本模型元信息行前缀
+const metaLinePrefix = "#meta"
+
+// ModelMeta 模型元信息
+// 默认FM模型不写元信息，文本和二进制格式与C++版本alphaFM保持完全兼容；
+// 其他模式下文本模型首行为"#meta k=v ..."，二进制模型使用version 2并在头部之后写入同样的k=v串
+type ModelMeta struct {
+	ModelType     string  // fm 或 ffm
+	FieldNum      int     // FFM的field数量
+	ClassNum      int     // 多分类的类别数，0表示二分类或回归
+	Optimizer     string  // 训练所用优化器，决定n、z槽位的含义
+	Loss          string  // 训练目标，决定预测时的link函数
+	OptimizerStep uint64  // Adam的全局步数
+	InputLines    int64   // 检查点写入时已训练的输入行数，用于断点续训
+	Hashed        bool    // 是否使用特征哈希
+	HashSeed      uint64  // 特征哈希的种子
+	HashBuckets   uint64  // 特征哈希的桶数，0表示使用完整的64位键
+	NegSampleRate float64 // 训练数据的负样本采样率，预测时据此校准，0表示未降采样
+}
+
+// NewModelMeta 创建默认元信息
+func NewModelMeta() ModelMeta {
+	return ModelMeta{ModelType: ModelTypeFM, Optimizer: OptimizerFTRL, Loss: LossLogistic}
+}
+
+// IsDefault 是否为默认FM模型（无需写元信息）
+func (m ModelMeta) IsDefault() bool {
+	return m == NewModelMeta()
+}
+
+// VecLen 每个特征的隐向量长度（FFM为field_num*factor_num）
+// 多分类模型为class_num*(factor_num+1)：前class_num个为各类别的w，之后依次为各类别的v
+func (m ModelMeta) VecLen(factorNum int) int {
+	if m.ModelType == ModelTypeFFM {
+		return m.FieldNum * factorNum
+	}
+	if m.ClassNum > 0 {
+		return m.ClassNum * (factorNum + 1)
+	}
+	return factorNum
+}
+
+// BiasVecLen bias单元的向量长度，多分类模型为各类别的bias，其他模型为0
+func (m ModelMeta) BiasVecLen() int {
+	return m.ClassNum
+}
+
+// String 序列化为k=v串
+func (m ModelMeta) String() string {
+	parts := []string{"model_type=" + m.ModelType}
+	if m.ModelType == ModelTypeFFM {
+		parts = append(parts, "field_num="+strconv.Itoa(m.FieldNum))
+	}
+	if m.ClassNum > 0 {
+		parts = append(parts, "class_num="+strconv.Itoa(m.ClassNum))
+	}
+	if m.Optimizer != OptimizerFTRL {
+		parts = append(parts, "optimizer="+m.Optimizer)
+	}
+	if m.Optimizer == OptimizerAdam {
+		parts = append(parts, "optimizer_step="+strconv.FormatUint(m.OptimizerStep, 10))
+	}
+	if m.Loss != LossLogistic {
+		parts = append(parts, "loss="+m.Loss)
+	}
+	if m.Hashed {
+		parts = append(parts, "hashed=1",
+			"hash_seed="+strconv.FormatUint(m.HashSeed, 10),
+			"hash_buckets="+strconv.FormatUint(m.HashBuckets, 10))
+	}
+	if m.NegSampleRate > 0 {
+		parts = append(parts, "neg_sample_rate="+strconv.FormatFloat(m.NegSampleRate, 'g', -1, 64))
+	}
+	if m.InputLines > 0 {
+		parts = append(parts, "input_lines="+strconv.FormatInt(m.InputLines, 10))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Hasher 根据哈希配置创建特征哈希，未开启时返回nil
+func (m ModelMeta) Hasher() *FeatureHasher {
+	if !m.Hashed {
+		return nil
+	}
+	return NewFeatureHasher(m.HashSeed, m.HashBuckets)
+}
+
+// CheckHashing 检查加载的模型与当前的特征哈希配置是否一致
+func (m ModelMeta) CheckHashing(loaded ModelMeta) error {
+	if m.Hashed != loaded.Hashed || m.HashSeed != loaded.HashSeed || m.HashBuckets != loaded.HashBuckets {
+		return fmt.Errorf("feature hashing mismatch: model=(hashed=%v seed=%d buckets=%d), expected=(hashed=%v seed=%d buckets=%d)",
+			loaded.Hashed, loaded.HashSeed, loaded.HashBuckets, m.Hashed, m.HashSeed, m.HashBuckets)
+	}
+	return nil
+}
+
+// Validate 检查元信息是否合法
+func (m ModelMeta) Validate() error {
+	switch m.ModelType {
+	case ModelTypeFM:
+	case ModelTypeFFM:
+		if m.FieldNum <= 0 {
+			return fmt.Errorf("field_num must be positive for ffm")
+		}
+	default:
+		return fmt.Errorf("unknown model_type: %s", m.ModelType)
+	}
+	if !IsValidOptimizer(m.Optimizer) {
+		return fmt.Errorf("unknown optimizer: %s", m.Optimizer)
+	}
+	if !IsValidLoss(m.Loss) {
+		return fmt.Errorf("unknown loss: %s", m.Loss)
+	}
+	if !ValidNegSampleRate(m.NegSampleRate) {
+		return fmt.Errorf("neg_sample_rate must be in [0, 1]: %v", m.NegSampleRate)
+	}
+	if m.NegSampleRate > 0 && m.Loss != LossLogistic {
+		return fmt.Errorf("neg_sample_rate only applies to the logistic loss")
+	}
+	if m.ClassNum != 0 {
+		if !ValidClassNum(m.ClassNum) {
+			return fmt.Errorf("class_num must be at least 2: %d", m.ClassNum)
+		}
+		if m.ModelType != ModelTypeFM || m.Loss != LossLogistic || m.NegSampleRate > 0 {
+			return fmt.Errorf("multiclass requires a plain fm model without loss or neg_sample_rate")
+		}
+	}
+	return nil
+}
+
+// CheckCompatible 检查加载的模型元信息与当前配置的结构是否一致（不含优化器和特征哈希）
+func (m ModelMeta) CheckCompatible(loaded ModelMeta) error {
+	if m.ModelType != loaded.ModelType {
+		return fmt.Errorf("model_type mismatch: model=%s, expected=%s", loaded.ModelType, m.ModelType)
+	}
+	if m.FieldNum != loaded.FieldNum {
+		return fmt.Errorf("field_num mismatch: model=%d, expected=%d", loaded.FieldNum, m.FieldNum)
+	}
+	if m.ClassNum != loaded.ClassNum {
+		return fmt.Errorf("class_num mismatch: model=%d, expected=%d", loaded.ClassNum, m.ClassNum)
+	}
+	return nil
+}
+
+// ValidNegSampleRate 负样本采样率是否合法，0表示未降采样
+func ValidNegSampleRate(rate float64) bool {
+	return rate >= 0 && rate <= 1
+}
+
+// CorrectNegSampling 负样本降采样的概率校准
+// 负样本按rate采样训练的模型预测值偏高，校准为q = p/(p+(1-p)/rate)；rate为0或1时原样返回
+func CorrectNegSampling(p, rate float64) float64 {
+	if rate <= 0 || rate >= 1 || math.IsNaN(p) {
+		return p
+	}
+	return p / (p + (1.0-p)/rate)
+}
+
+// ParseModelMeta 解析k=v串
+func ParseModelMeta(s string) (ModelMeta, error) {
+	meta := NewModelMeta()
+	for _, kv := range strings.Fields(s) {
+		idx := strings.IndexByte(kv, '=')
+		if idx <= 0 {
+			return meta, fmt.Errorf("invalid meta item: %s", kv)
+		}
+		key, value := kv[:idx], kv[idx+1:]
+
+		var err error
+		switch key {
+		case "model_type":
+			meta.ModelType = value
+		case "field_num":
+			meta.FieldNum, err = strconv.Atoi(value)
+		case "class_num":
+			meta.ClassNum, err = strconv.Atoi(value)
+		case "optimizer":
+			meta.Optimizer = value
+		case "loss":
+			meta.Loss = value
+		case "optimizer_step":
+			meta.OptimizerStep, err = strconv.ParseUint(value, 10, 64)
+		case "input_lines":
+			meta.InputLines, err = strconv.ParseInt(value, 10, 64)
+		case "hashed":
+			meta.Hashed = value == "1"
+		case "hash_seed":
+			meta.HashSeed, err = strconv.ParseUint(value, 10, 64)
+		case "hash_buckets":
+			meta.HashBuckets, err = strconv.ParseUint(value, 10, 64)
+		case "neg_sample_rate":
+			meta.NegSampleRate, err = strconv.ParseFloat(value, 64)
+		default:
+			return meta, fmt.Errorf("unknown meta item: %s", key)
+		}
+		if err != nil {
+			return meta, fmt.Errorf("invalid meta item %s: %v", kv, err)
+		}
+	}
+	return meta, meta.Validate()
+}
+
+// isMetaLine 判断文本模型行是否为元信息行
+func isMetaLine(line string) bool {
+	return strings.HasPrefix(line, metaLinePrefix)
+}
+
+// metaLine 生成文本模型元信息行
+func metaLine(meta ModelMeta) string {
+	return metaLinePrefix + " " + meta.String()
+}
+
+// parseMetaLine 解析文本模型元信息行
+func parseMetaLine(line string) (ModelMeta, error) {
+	return ParseModelMeta(strings.TrimPrefix(line, metaLinePrefix))
+}
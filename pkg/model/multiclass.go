@@ -0,0 +1,188 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// 多分类模型中每个类别是一个独立的FM：特征单元的Vi[c]为类别c的w，
+// Vi[C+c*k : C+(c+1)*k]为类别c的隐向量，bias单元的Vi[c]为类别c的bias，单元自身的Wi不使用。
+// 各类别的得分经softmax得到类别概率，按交叉熵训练
+
+// ValidClassNum 多分类的类别数是否合法
+func ValidClassNum(classNum int) bool {
+	return classNum >= 2
+}
+
+// ClassLabel 把原始标签转换为类别序号，标签必须是[0, classNum)内的整数
+func ClassLabel(label float64, classNum int) (int, error) {
+	c := int(label)
+	if float64(c) != label || c < 0 || c >= classNum {
+		return 0, fmt.Errorf("invalid class label %g, expected an integer in [0, %d)", label, classNum)
+	}
+	return c, nil
+}
+
+// softmax 把各类别的得分原地转换为概率，返回log(Σexp(score))
+func softmax(scores []float64) float64 {
+	max := scores[0]
+	for _, s := range scores[1:] {
+		max = math.Max(max, s)
+	}
+	sum := 0.0
+	for c, s := range scores {
+		scores[c] = math.Exp(s - max)
+		sum += scores[c]
+	}
+	for c := range scores {
+		scores[c] /= sum
+	}
+	return max + math.Log(sum)
+}
+
+// classScores 计算各类别的得分和各类别的Σv*x（按类别依次存放，每类k个）
+func classScores(bias []float64, vecs [][]float64, x []sample.FeatureValue, k int) ([]float64, []float64) {
+	classNum := len(bias)
+	scores := make([]float64, classNum)
+	sums := make([]float64, classNum*k)
+	for c := 0; c < classNum; c++ {
+		score := bias[c]
+		sum := sums[c*k : (c+1)*k]
+		for i, vi := range vecs {
+			xi := x[i].Value
+			score += vi[c] * xi
+			v := vi[classNum+c*k : classNum+(c+1)*k]
+			for f := 0; f < k; f++ {
+				d := v[f] * xi
+				sum[f] += d
+				score -= 0.5 * d * d
+			}
+		}
+		for f := 0; f < k; f++ {
+			score += 0.5 * sum[f] * sum[f]
+		}
+		scores[c] = score
+	}
+	return scores, sums
+}
+
+// runMulticlassSamples 按softmax交叉熵训练一批多分类样本
+func (t *FTRLTrainer) runMulticlassSamples(batch []*sample.FMSample, progressive bool) error {
+	if t.opt.evictionEnabled() {
+		atomic.AddInt64(&t.seen, int64(len(batch)))
+	}
+
+	lossSum, weightSum := 0.0, 0.0
+	for _, s := range batch {
+		label, err := ClassLabel(s.Label, t.model.Meta.ClassNum)
+		if err != nil {
+			return err
+		}
+		lossSum += s.W * t.trainMulticlass(label, s.W, s.X)
+		weightSum += s.W
+	}
+
+	if progressive {
+		t.progressive.AddLoss(int64(len(batch)), lossSum, weightSum)
+	}
+	t.lossMu.Lock()
+	t.lossSum += lossSum
+	t.lossWeight += weightSum
+	t.lossNum += int64(len(batch))
+	t.lossMu.Unlock()
+	return nil
+}
+
+// trainMulticlass 训练一个多分类样本，返回更新前预测的交叉熵
+// 类别c得分的梯度为weight*(p_c - [c==label])，各类别的w和bias按一阶权重的规则更新
+func (t *FTRLTrainer) trainMulticlass(label int, weight float64, x []sample.FeatureValue) float64 {
+	thetaBias := t.model.GetOrInitModelUnitBias()
+	x, theta, feaLocks := t.getUnitsAndLocks(x)
+	xLen := len(x)
+	classNum := t.model.Meta.ClassNum
+	k := t.model.FactorNum
+	t.tick()
+
+	// 更新w和v
+	if t.opt.K0 {
+		feaLocks[xLen].Lock()
+		for c := 0; c < classNum; c++ {
+			t.optimizer.PrepareW(&thetaBias.Vi[c], &thetaBias.VNi[c], &thetaBias.VZi[c])
+		}
+		feaLocks[xLen].Unlock()
+	}
+	vecs := make([][]float64, xLen)
+	for i, mu := range theta {
+		feaLocks[i].Lock()
+		if t.opt.K1 {
+			for c := 0; c < classNum; c++ {
+				t.optimizer.PrepareW(&mu.Vi[c], &mu.VNi[c], &mu.VZi[c])
+			}
+		}
+		for f := classNum; f < len(mu.Vi); f++ {
+			t.optimizer.PrepareV(&mu.Vi[f], &mu.VNi[f], &mu.VZi[f])
+		}
+		feaLocks[i].Unlock()
+		vecs[i] = mu.Vi
+	}
+
+	// 预测
+	scores, sums := classScores(thetaBias.Vi, vecs, x, k)
+	labelScore := scores[label]
+	loss := softmax(scores) - labelScore
+
+	// 各类别得分的梯度，复用概率数组
+	grads := scores
+	for c := range grads {
+		grads[c] *= weight
+	}
+	grads[label] -= weight
+
+	// 更新bias
+	if t.opt.K0 {
+		feaLocks[xLen].Lock()
+		for c := 0; c < classNum; c++ {
+			t.optimizer.UpdateW(&thetaBias.Vi[c], &thetaBias.VNi[c], &thetaBias.VZi[c], grads[c])
+		}
+		feaLocks[xLen].Unlock()
+	}
+
+	// 更新各类别的w和v
+	for i, mu := range theta {
+		xi := x[i].Value
+		feaLocks[i].Lock()
+		for c := 0; c < classNum; c++ {
+			if t.opt.K1 {
+				t.optimizer.UpdateW(&mu.Vi[c], &mu.VNi[c], &mu.VZi[c], grads[c]*xi)
+			}
+			sum := sums[c*k : (c+1)*k]
+			base := classNum + c*k
+			for f := 0; f < k; f++ {
+				j := base + f
+				vGif := grads[c] * (sum[f]*xi - mu.Vi[j]*xi*xi)
+				t.optimizer.UpdateV(&mu.Vi[j], &mu.VNi[j], &mu.VZi[j], vGif)
+			}
+		}
+		feaLocks[i].Unlock()
+	}
+
+	return loss
+}
+
+// GetClassProbs 计算多分类模型各类别的概率，不在模型中的特征忽略
+func (m *PredictModel) GetClassProbs(x []sample.FeatureValue) []float64 {
+	vecs := make([][]float64, 0, len(x))
+	xs := make([]sample.FeatureValue, 0, len(x))
+	for i := range x {
+		if unit, ok := m.store.get(x[i].Feature); ok {
+			vecs = append(vecs, unit.Vi)
+			xs = append(xs, x[i])
+		}
+	}
+	probs, _ := classScores(m.MuBias.Vi, vecs, xs, m.FactorNum)
+	softmax(probs)
+	return probs
+}
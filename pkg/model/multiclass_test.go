@@ -0,0 +1,81 @@
+package model
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+func TestMulticlassTraining(t *testing.T) {
+	// a、b、c分别对应类别0、1、2，a和b同时出现时为类别2
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, "0 a:1", "1 b:1", "2 c:1", "2 a:1 b:1")
+	}
+	lines = append(lines, "5 c:1", "1.5 a:1")
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.ClassNum = 3
+	opt.ThreadsNum = 1
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(lines); err != nil {
+		t.Fatal(err)
+	}
+	// 不合法的类别标签被跳过
+	if _, num := trainer.TakeTrainLoss(); num != 4000 {
+		t.Fatalf("trained %d samples, want 4000", num)
+	}
+
+	for _, format := range []string{"txt", "bin"} {
+		path := filepath.Join(t.TempDir(), "model."+format)
+		if err := trainer.OutputModel(path, format); err != nil {
+			t.Fatal(err)
+		}
+		wrong := NewPredictModel(2)
+		wrong.Meta.ClassNum = 4
+		if err := wrong.LoadModel(path, format); err == nil {
+			t.Fatalf("%s: loading with a different class num should fail", format)
+		}
+		// 未指定类别数时以模型的元信息为准
+		pm := NewPredictModel(2)
+		if err := pm.LoadModel(path, format); err != nil || pm.Meta.ClassNum != 3 {
+			t.Fatalf("%s: class num %d, error %v", format, pm.Meta.ClassNum, err)
+		}
+
+		cases := []struct {
+			x     []sample.FeatureValue
+			class int
+		}{
+			{[]sample.FeatureValue{{Feature: "a", Value: 1}}, 0},
+			{[]sample.FeatureValue{{Feature: "b", Value: 1}}, 1},
+			{[]sample.FeatureValue{{Feature: "c", Value: 1}}, 2},
+			{[]sample.FeatureValue{{Feature: "a", Value: 1}, {Feature: "b", Value: 1}}, 2},
+		}
+		for _, c := range cases {
+			probs := pm.GetClassProbs(c.x)
+			sum, best := 0.0, 0
+			for k, p := range probs {
+				sum += p
+				if p > probs[best] {
+					best = k
+				}
+			}
+			if math.Abs(sum-1) > 1e-9 || best != c.class || probs[best] < 0.5 {
+				t.Errorf("%s: probs of %v = %v, want class %d", format, c.x, probs, c.class)
+			}
+		}
+	}
+}
+
+func TestClassLabel(t *testing.T) {
+	if c, err := ClassLabel(2, 3); err != nil || c != 2 {
+		t.Fatalf("ClassLabel(2, 3) = %d, %v", c, err)
+	}
+	for _, label := range []float64{-1, 3, 0.5} {
+		if _, err := ClassLabel(label, 3); err == nil {
+			t.Errorf("label %v should be rejected", label)
+		}
+	}
+}
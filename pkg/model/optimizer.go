@@ -0,0 +1,226 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/xiongle/alphaFM-go/pkg/utils"
+)
+
+// 优化器类型
+const (
+	OptimizerFTRL    = "ftrl"
+	OptimizerAdaGrad = "adagrad"
+	OptimizerAdam    = "adam"
+	OptimizerSGD     = "sgd"
+)
+
+// OptimizerParam 一组参数（w或v）的超参数
+type OptimizerParam struct {
+	Alpha float64 // 学习率
+	Beta  float64 // FTRL/AdaGrad的学习率平滑项
+	L1    float64 // L1正则（仅FTRL）
+	L2    float64 // L2正则
+}
+
+// Optimizer 参数更新规则
+// 每个参数有两个状态槽位n、z，对应FTRLModelUnit中的WNi/WZi和VNi/VZi，由各优化器自行解释：
+//
+//	ftrl:    n=梯度平方和, z=FTRL的z
+//	adagrad: n=梯度平方和, z未使用
+//	adam:    n=二阶矩,     z=一阶矩
+//	sgd:     n、z均未使用
+//
+// 调用方负责加锁
+type Optimizer interface {
+	// Name 优化器名称
+	Name() string
+	// PrepareW 预测前由状态计算w（FTRL的惰性求解，其他优化器无操作）
+	PrepareW(w, n, z *float64)
+	// PrepareV 预测前由状态计算v的一维
+	PrepareV(v, n, z *float64)
+	// UpdateW 根据梯度更新w及其状态
+	UpdateW(w, n, z *float64, g float64)
+	// UpdateV 根据梯度更新v的一维及其状态
+	UpdateV(v, n, z *float64, g float64)
+}
+
+// stepOptimizer 需要全局步数的优化器（Adam偏差修正）
+type stepOptimizer interface {
+	// Tick 每个训练样本调用一次
+	Tick()
+	// Steps 当前全局步数
+	Steps() uint64
+	// SetSteps 恢复全局步数（增量训练）
+	SetSteps(steps uint64)
+}
+
+// IsValidOptimizer 判断优化器名称是否合法
+func IsValidOptimizer(name string) bool {
+	switch name {
+	case OptimizerFTRL, OptimizerAdaGrad, OptimizerAdam, OptimizerSGD:
+		return true
+	}
+	return false
+}
+
+// NewOptimizer 根据训练选项创建优化器
+func NewOptimizer(opt *TrainerOption) (Optimizer, error) {
+	wParam := OptimizerParam{Alpha: opt.WAlpha, Beta: opt.WBeta, L1: opt.WL1, L2: opt.WL2}
+	vParam := OptimizerParam{Alpha: opt.VAlpha, Beta: opt.VBeta, L1: opt.VL1, L2: opt.VL2}
+
+	switch opt.Optimizer {
+	case OptimizerFTRL, "":
+		return &ftrlOptimizer{w: wParam, v: vParam}, nil
+	case OptimizerAdaGrad:
+		return &adaGradOptimizer{w: wParam, v: vParam}, nil
+	case OptimizerAdam:
+		a := &adamOptimizer{w: wParam, v: vParam, beta1: opt.AdamBeta1, beta2: opt.AdamBeta2, eps: opt.AdamEps}
+		a.SetSteps(0)
+		return a, nil
+	case OptimizerSGD:
+		return &sgdOptimizer{w: wParam, v: vParam}, nil
+	}
+	return nil, fmt.Errorf("unknown optimizer: %s (available: ftrl, adagrad, adam, sgd)", opt.Optimizer)
+}
+
+// ftrlOptimizer FTRL-Proximal
+type ftrlOptimizer struct {
+	w, v OptimizerParam
+}
+
+func (o *ftrlOptimizer) Name() string { return OptimizerFTRL }
+
+// ftrlSolve 由z、n求解参数
+func ftrlSolve(p OptimizerParam, n, z float64) float64 {
+	if math.Abs(z) <= p.L1 {
+		return 0.0
+	}
+	return -1.0 * (1.0 / (p.L2 + (p.Beta+math.Sqrt(n))/p.Alpha)) *
+		(z - float64(utils.Sgn(z))*p.L1)
+}
+
+// ftrlUpdate 根据梯度更新z、n
+func ftrlUpdate(p OptimizerParam, w, n, z *float64, g float64) {
+	s := (1.0 / p.Alpha) * (math.Sqrt(*n+g*g) - math.Sqrt(*n))
+	*z += g - s*(*w)
+	*n += g * g
+}
+
+func (o *ftrlOptimizer) PrepareW(w, n, z *float64) {
+	*w = ftrlSolve(o.w, *n, *z)
+}
+
+func (o *ftrlOptimizer) PrepareV(v, n, z *float64) {
+	// 未更新过的v保留随机初始值
+	if *n > 0 {
+		*v = ftrlSolve(o.v, *n, *z)
+	}
+}
+
+func (o *ftrlOptimizer) UpdateW(w, n, z *float64, g float64) {
+	ftrlUpdate(o.w, w, n, z, g)
+}
+
+func (o *ftrlOptimizer) UpdateV(v, n, z *float64, g float64) {
+	ftrlUpdate(o.v, v, n, z, g)
+}
+
+// adaGradOptimizer AdaGrad（带L2），学习率为 alpha / (beta + sqrt(n))
+type adaGradOptimizer struct {
+	w, v OptimizerParam
+}
+
+func (o *adaGradOptimizer) Name() string { return OptimizerAdaGrad }
+
+func adaGradUpdate(p OptimizerParam, w, n *float64, g float64) {
+	g += p.L2 * (*w)
+	*n += g * g
+	*w -= p.Alpha / (p.Beta + math.Sqrt(*n)) * g
+}
+
+func (o *adaGradOptimizer) PrepareW(w, n, z *float64) {}
+
+func (o *adaGradOptimizer) PrepareV(v, n, z *float64) {}
+
+func (o *adaGradOptimizer) UpdateW(w, n, z *float64, g float64) {
+	adaGradUpdate(o.w, w, n, g)
+}
+
+func (o *adaGradOptimizer) UpdateV(v, n, z *float64, g float64) {
+	adaGradUpdate(o.v, v, n, g)
+}
+
+// adamOptimizer Adam（带L2），偏差修正使用按样本计数的全局步数
+type adamOptimizer struct {
+	w, v         OptimizerParam
+	beta1, beta2 float64
+	eps          float64
+	steps        uint64
+	corr1, corr2 uint64 // 偏差修正系数 1-beta^t 的float64位表示
+}
+
+func (o *adamOptimizer) Name() string { return OptimizerAdam }
+
+func (o *adamOptimizer) Tick() {
+	o.storeCorrection(atomic.AddUint64(&o.steps, 1))
+}
+
+func (o *adamOptimizer) Steps() uint64 {
+	return atomic.LoadUint64(&o.steps)
+}
+
+func (o *adamOptimizer) SetSteps(steps uint64) {
+	atomic.StoreUint64(&o.steps, steps)
+	o.storeCorrection(steps)
+}
+
+func (o *adamOptimizer) storeCorrection(t uint64) {
+	// t=0时（尚未Tick）按第一步处理，避免除零
+	if t == 0 {
+		t = 1
+	}
+	atomic.StoreUint64(&o.corr1, math.Float64bits(1.0-math.Pow(o.beta1, float64(t))))
+	atomic.StoreUint64(&o.corr2, math.Float64bits(1.0-math.Pow(o.beta2, float64(t))))
+}
+
+func (o *adamOptimizer) update(p OptimizerParam, w, n, z *float64, g float64) {
+	g += p.L2 * (*w)
+	*z = o.beta1*(*z) + (1.0-o.beta1)*g
+	*n = o.beta2*(*n) + (1.0-o.beta2)*g*g
+	mHat := *z / math.Float64frombits(atomic.LoadUint64(&o.corr1))
+	vHat := *n / math.Float64frombits(atomic.LoadUint64(&o.corr2))
+	*w -= p.Alpha * mHat / (math.Sqrt(vHat) + o.eps)
+}
+
+func (o *adamOptimizer) PrepareW(w, n, z *float64) {}
+
+func (o *adamOptimizer) PrepareV(v, n, z *float64) {}
+
+func (o *adamOptimizer) UpdateW(w, n, z *float64, g float64) {
+	o.update(o.w, w, n, z, g)
+}
+
+func (o *adamOptimizer) UpdateV(v, n, z *float64, g float64) {
+	o.update(o.v, v, n, z, g)
+}
+
+// sgdOptimizer 带L2的普通SGD
+type sgdOptimizer struct {
+	w, v OptimizerParam
+}
+
+func (o *sgdOptimizer) Name() string { return OptimizerSGD }
+
+func (o *sgdOptimizer) PrepareW(w, n, z *float64) {}
+
+func (o *sgdOptimizer) PrepareV(v, n, z *float64) {}
+
+func (o *sgdOptimizer) UpdateW(w, n, z *float64, g float64) {
+	*w -= o.w.Alpha * (g + o.w.L2*(*w))
+}
+
+func (o *sgdOptimizer) UpdateV(v, n, z *float64, g float64) {
+	*v -= o.v.Alpha * (g + o.v.L2*(*v))
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// genFMLines 生成一阶可分的数据: 含pos特征的为正样本
+func genFMLines(n int, r *rand.Rand) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		if r.Intn(2) == 0 {
+			lines[i] = "1 pos:1 noise" + string(rune('a'+r.Intn(5))) + ":1"
+		} else {
+			lines[i] = "0 neg:1 noise" + string(rune('a'+r.Intn(5))) + ":1"
+		}
+	}
+	return lines
+}
+
+func TestOptimizersLearn(t *testing.T) {
+	for _, name := range []string{OptimizerFTRL, OptimizerAdaGrad, OptimizerAdam, OptimizerSGD} {
+		rand.Seed(1)
+		r := rand.New(rand.NewSource(1))
+
+		opt := NewTrainerOption()
+		opt.FactorNum = 2
+		opt.Optimizer = name
+		opt.WL1, opt.VL1 = 0, 0
+		opt.WL2, opt.VL2 = 0, 0
+		opt.WAlpha, opt.VAlpha = 0.05, 0.05
+		trainer := NewFTRLTrainer(opt)
+		if trainer.optimizer.Name() != name {
+			t.Fatalf("optimizer %s not selected", name)
+		}
+		if err := trainer.RunTask(genFMLines(2000, r)); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(t.TempDir(), "model.txt")
+		if err := trainer.OutputModel(path, "txt"); err != nil {
+			t.Fatal(err)
+		}
+		pm := NewPredictModel(2)
+		if err := pm.LoadModel(path, "txt"); err != nil {
+			t.Fatalf("%s: load model failed: %v", name, err)
+		}
+		if pm.Meta.Optimizer != name {
+			t.Errorf("%s: optimizer not recorded in meta: %s", name, pm.Meta.Optimizer)
+		}
+
+		for _, line := range []string{"1 pos:1 noisea:1", "0 neg:1 noiseb:1"} {
+			s, _ := sample.ParseSample(line, sample.LabelBinary)
+			x := make([]struct {
+				Feature string
+				Value   float64
+			}, len(s.X))
+			for i := range s.X {
+				x[i].Feature, x[i].Value = s.X[i].Feature, s.X[i].Value
+			}
+			score := pm.GetScore(x, pm.MuBias.Wi)
+			if (score > 0.5) != (s.Y > 0) {
+				t.Errorf("%s: wrong prediction %.4f for %q", name, score, line)
+			}
+		}
+
+		// 增量训练时优化器必须一致
+		other := NewTrainerOption()
+		other.FactorNum = 2
+		if name == OptimizerFTRL {
+			other.Optimizer = OptimizerSGD
+		}
+		if err := NewFTRLTrainer(other).LoadModel(path, "txt"); err == nil {
+			t.Errorf("%s: loading with optimizer %s should fail", name, other.Optimizer)
+		}
+	}
+}
+
+func TestAdamStepsPersisted(t *testing.T) {
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.Optimizer = OptimizerAdam
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.RunTask(genFMLines(100, rand.New(rand.NewSource(1)))); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.bin")
+	if err := trainer.OutputModel(path, "bin"); err != nil {
+		t.Fatal(err)
+	}
+	resumed := NewFTRLTrainer(opt)
+	if err := resumed.LoadModel(path, "bin"); err != nil {
+		t.Fatal(err)
+	}
+	if steps := resumed.optimizer.(stepOptimizer).Steps(); steps != 100 {
+		t.Errorf("adam steps not restored: %d", steps)
+	}
+}
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// 成对排序训练的目标
+const (
+	RankBPR     = "bpr"     // 每个正样本与同组内随机采样的负样本组成样本对
+	RankRankNet = "ranknet" // 同组内所有标签不同的样本两两组成样本对
+)
+
+// IsValidRank 判断成对排序目标名称是否合法
+func IsValidRank(name string) bool {
+	return name == RankBPR || name == RankRankNet
+}
+
+// rankPair 分组内的一个样本对，pos的排序应当高于neg
+type rankPair struct {
+	pos, neg *sample.FMSample
+}
+
+// groupPairs 由同一分组的样本生成样本对
+// ranknet按原始标签比较，支持分级标签；bpr按正负样本，每个正样本采样RankNeg个负样本（负样本不足时全部使用），
+// 采样的随机源由种子和分组键决定
+func (t *FTRLTrainer) groupPairs(group []*sample.FMSample) []rankPair {
+	var pairs []rankPair
+	if t.opt.Rank == RankRankNet {
+		for _, si := range group {
+			for _, sj := range group {
+				if si.Label > sj.Label {
+					pairs = append(pairs, rankPair{si, sj})
+				}
+			}
+		}
+		return pairs
+	}
+
+	var negs []*sample.FMSample
+	for _, s := range group {
+		if s.Y < 0 {
+			negs = append(negs, s)
+		}
+	}
+	if len(negs) == 0 {
+		return nil
+	}
+	rng := newInitRNG(uint64(t.opt.Seed), nameHash(group[0].Group))
+	for _, s := range group {
+		if s.Y < 0 {
+			continue
+		}
+		if t.opt.RankNeg >= len(negs) {
+			for _, neg := range negs {
+				pairs = append(pairs, rankPair{s, neg})
+			}
+			continue
+		}
+		for n := 0; n < t.opt.RankNeg; n++ {
+			pairs = append(pairs, rankPair{s, negs[rng.next()%uint64(len(negs))]})
+		}
+	}
+	return pairs
+}
+
+// runRankSamples 成对排序训练一批样本，批次中Group相同的连续样本为一个分组
+// 训练损失和渐进式验证按样本对统计
+func (t *FTRLTrainer) runRankSamples(batch []*sample.FMSample, progressive bool) error {
+	// bias在得分差中抵消，保持初始值，模型文件仍需要bias行
+	t.model.GetOrInitModelUnitBias()
+	if t.opt.evictionEnabled() {
+		atomic.AddInt64(&t.seen, int64(len(batch)))
+	}
+
+	lossSum, weightSum := 0.0, 0.0
+	pairNum := 0
+	for start := 0; start < len(batch); {
+		end := start + 1
+		for end < len(batch) && batch[end].Group == batch[start].Group {
+			end++
+		}
+		for _, pair := range t.groupPairs(batch[start:end]) {
+			weight := pair.pos.W * pair.neg.W
+			d := t.trainPair(pair.pos.X, pair.neg.X, weight)
+			lossSum += weight * logLoss(d, 1)
+			weightSum += weight
+			pairNum++
+		}
+		start = end
+	}
+
+	if progressive {
+		t.progressive.AddLoss(int64(pairNum), lossSum, weightSum)
+	}
+	t.lossMu.Lock()
+	t.lossSum += lossSum
+	t.lossWeight += weightSum
+	t.lossNum += int64(pairNum)
+	t.lossMu.Unlock()
+	return nil
+}
+
+// trainPair 训练一个样本对，损失为log(1+exp(-d))，d为两个样本FM得分之差，返回更新前的d
+// 两个样本共有的特征合并为一个单元只更新一次，bias在得分差中抵消，不参与训练
+func (t *FTRLTrainer) trainPair(pos, neg []sample.FeatureValue, weight float64) float64 {
+	pos, thetaPos, locksPos := t.getUnitsAndLocks(pos)
+	neg, thetaNeg, locksNeg := t.getUnitsAndLocks(neg)
+	t.tick()
+
+	// 合并两个样本的特征，a、b为特征在pos、neg中的取值
+	n := len(thetaPos) + len(thetaNeg)
+	index := make(map[*FTRLModelUnit]int, n)
+	theta := make([]*FTRLModelUnit, 0, n)
+	locks := make([]sync.Locker, 0, n)
+	a := make([]float64, 0, n)
+	b := make([]float64, 0, n)
+	merge := func(x []sample.FeatureValue, units []*FTRLModelUnit, feaLocks []sync.Locker, values *[]float64) {
+		for i, mu := range units {
+			j, ok := index[mu]
+			if !ok {
+				j = len(theta)
+				index[mu] = j
+				theta = append(theta, mu)
+				locks = append(locks, feaLocks[i])
+				a = append(a, 0)
+				b = append(b, 0)
+			}
+			(*values)[j] += x[i].Value
+		}
+	}
+	merge(pos, thetaPos, locksPos, &a)
+	merge(neg, thetaNeg, locksNeg, &b)
+
+	// 更新w和v
+	k := t.model.FactorNum
+	for i, mu := range theta {
+		locks[i].Lock()
+		if t.opt.K1 {
+			t.prepareW(mu)
+		}
+		for f := 0; f < k; f++ {
+			t.updateVi(mu, f)
+		}
+		locks[i].Unlock()
+	}
+
+	// 得分差
+	d := 0.0
+	for i, mu := range theta {
+		d += mu.Wi * (a[i] - b[i])
+	}
+	sumA := make([]float64, k)
+	sumB := make([]float64, k)
+	for f := 0; f < k; f++ {
+		sqrA, sqrB := 0.0, 0.0
+		for i, mu := range theta {
+			da, db := mu.Vi[f]*a[i], mu.Vi[f]*b[i]
+			sumA[f] += da
+			sumB[f] += db
+			sqrA += da * da
+			sqrB += db * db
+		}
+		d += 0.5 * (sumA[f]*sumA[f] - sqrA - sumB[f]*sumB[f] + sqrB)
+	}
+
+	// 得分差的梯度分别作用于两个样本的特征
+	mult := weight * logisticLoss{}.Gradient(d, 1)
+	for i, mu := range theta {
+		locks[i].Lock()
+		if t.opt.K1 && a[i] != b[i] {
+			t.optimizer.UpdateW(&mu.Wi, &mu.WNi, &mu.WZi, mult*(a[i]-b[i]))
+		}
+		for f := 0; f < k; f++ {
+			vGif := mult * (a[i]*(sumA[f]-mu.Vi[f]*a[i]) - b[i]*(sumB[f]-mu.Vi[f]*b[i]))
+			t.updateViGradient(mu, f, vGif)
+		}
+		locks[i].Unlock()
+	}
+	return d
+}
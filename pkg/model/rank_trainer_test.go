@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+func TestGroupPairs(t *testing.T) {
+	group := []*sample.FMSample{
+		{Label: 2, Y: 1, Group: "q"},
+		{Label: 1, Y: 1, Group: "q"},
+		{Label: 0, Y: -1, Group: "q"},
+		{Label: 0, Y: -1, Group: "q"},
+	}
+	cases := []struct {
+		rank    string
+		rankNeg int
+		want    int
+	}{
+		{RankRankNet, 1, 5}, // 2>1, 2>0, 2>0, 1>0, 1>0
+		{RankBPR, 1, 2},
+		{RankBPR, 5, 4}, // 负样本不足时全部使用
+	}
+	for _, c := range cases {
+		opt := NewTrainerOption()
+		opt.Rank = c.rank
+		opt.RankNeg = c.rankNeg
+		pairs := NewFTRLTrainer(opt).groupPairs(group)
+		if len(pairs) != c.want {
+			t.Errorf("%s rank_neg=%d: %d pairs, want %d", c.rank, c.rankNeg, len(pairs), c.want)
+		}
+		for _, p := range pairs {
+			if p.pos.Label <= p.neg.Label {
+				t.Errorf("%s: pair out of order: %v, %v", c.rank, p.pos.Label, p.neg.Label)
+			}
+		}
+	}
+}
+
+func TestRankTraining(t *testing.T) {
+	// 同组内good排在bad之前，ctx在两个样本中取值相同
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines,
+			fmt.Sprintf("q%d 1 ctx:1 good:1", i),
+			fmt.Sprintf("q%d 0 ctx:1 bad:1", i))
+	}
+	for _, rank := range []string{RankBPR, RankRankNet} {
+		opt := NewTrainerOption()
+		opt.FactorNum = 2
+		opt.Rank = rank
+		opt.ThreadsNum = 1
+		trainer := NewFTRLTrainer(opt)
+		if err := trainer.RunTask(lines); err != nil {
+			t.Fatal(err)
+		}
+
+		good, _ := trainer.model.GetModelUnit("good")
+		bad, _ := trainer.model.GetModelUnit("bad")
+		ctx, _ := trainer.model.GetModelUnit("ctx")
+		if good.Wi <= bad.Wi {
+			t.Errorf("%s: good=%v, bad=%v", rank, good.Wi, bad.Wi)
+		}
+		// 得分差中抵消的ctx一阶项和bias不更新
+		if ctx.WNi != 0 || trainer.model.MuBias.WNi != 0 {
+			t.Errorf("%s: ctx n=%v, bias n=%v", rank, ctx.WNi, trainer.model.MuBias.WNi)
+		}
+		loss, num := trainer.TakeTrainLoss()
+		if num != 500 || loss <= 0 || loss >= logLoss(0, 1) {
+			t.Errorf("%s: train loss = %v over %d pairs", rank, loss, num)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"sync"
+
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// syncSample 批同步训练中一个样本的中间结果
+type syncSample struct {
+	x      []sample.FeatureValue // 去掉未准入特征后的特征
+	theta  []*FTRLModelUnit
+	shards []int
+	p      float64
+	mult   float64
+	vGrad  []float64 // 各特征隐向量的梯度，按特征依次排列
+}
+
+// trainSync 批同步训练（确定性模式），返回每个样本更新前的线性预测值（未经link函数）
+// 样本按SyncBatch分成小批，每个小批分四个阶段，阶段之间同步：
+//  1. 并行查找或创建特征单元（新特征按特征哈希确定性初始化，与创建顺序无关）
+//  2. 按分片划分单元，各线程按输入顺序计算w、v（FTRL由z、n求解）
+//  3. 并行地用小批开始时的参数计算预测值和梯度，只读不写
+//  4. 按分片划分单元，各线程按输入顺序把梯度交给优化器
+//
+// 每个单元的更新顺序只取决于输入顺序，结果与线程数和调度无关
+func (t *FTRLTrainer) trainSync(batch []*sample.FMSample) []float64 {
+	ps := make([]float64, len(batch))
+	size := t.opt.SyncBatch
+	if size <= 0 {
+		size = len(batch)
+	}
+	for start := 0; start < len(batch); start += size {
+		end := start + size
+		if end > len(batch) {
+			end = len(batch)
+		}
+		t.trainSyncBatch(batch[start:end], ps[start:end])
+	}
+	return ps
+}
+
+func (t *FTRLTrainer) trainSyncBatch(batch []*sample.FMSample, ps []float64) {
+	threads := t.opt.ThreadsNum
+	if threads < 1 {
+		threads = 1
+	}
+	thetaBias := t.model.GetOrInitModelUnitBias()
+	items := make([]syncSample, len(batch))
+
+	// 1. 查找或创建特征单元，开启准入时按输入顺序执行，使准入结果与线程数无关
+	resolveThreads := threads
+	if t.model.admission != nil {
+		resolveThreads = 1
+	}
+	stamp := t.stamp()
+	parallelRange(len(batch), resolveThreads, func(i int) {
+		x := batch[i].X
+		it := &items[i]
+		it.theta = make([]*FTRLModelUnit, 0, len(x))
+		it.shards = make([]int, 0, len(x))
+		it.x = x[:0:0]
+		for j := range x {
+			unit, shard := t.model.GetOrInitModelUnitIndexed(x[j].Feature)
+			if unit == nil {
+				continue
+			}
+			if stamp >= 0 {
+				unit.touch(stamp)
+			}
+			it.x = append(it.x, x[j])
+			it.theta = append(it.theta, unit)
+			it.shards = append(it.shards, shard)
+		}
+	})
+
+	// 2. 计算w、v
+	parallelShards(threads, func(owner int) {
+		if owner == 0 && t.opt.K0 {
+			t.prepareSyncW(thetaBias)
+		}
+		for i := range items {
+			it := &items[i]
+			for j, mu := range it.theta {
+				if it.shards[j]%threads != owner {
+					continue
+				}
+				if t.opt.K1 {
+					t.prepareSyncW(mu)
+				}
+				for f := range mu.Vi {
+					t.updateVi(mu, f)
+				}
+			}
+		}
+	})
+
+	// 3. 预测并计算梯度
+	k := t.model.FactorNum
+	bias := thetaBias.Wi
+	parallelRange(len(batch), threads, func(i int) {
+		s, it := batch[i], &items[i]
+		x := it.x
+		var p float64
+		var sum []float64
+		if t.useSIMD && len(x) > 0 {
+			p, sum = t.predictAndSumSIMD(x, bias, it.theta)
+		} else {
+			p = t.predictScalar(x, bias, it.theta)
+			sum = make([]float64, k)
+			for f := 0; f < k; f++ {
+				for j := range x {
+					sum[f] += it.theta[j].Vi[f] * x[j].Value
+				}
+			}
+		}
+		it.p = p
+		it.mult = s.W * t.loss.Gradient(p, s.Label)
+		it.vGrad = make([]float64, len(x)*k)
+		for j := range x {
+			xi := x[j].Value
+			vi := it.theta[j].Vi
+			for f := 0; f < k; f++ {
+				it.vGrad[j*k+f] = it.mult * (sum[f]*xi - vi[f]*xi*xi)
+			}
+		}
+	})
+
+	// Adam的步数在更新前一次推进整个小批，更新期间偏差修正保持不变
+	for range batch {
+		t.tick()
+	}
+
+	// 4. 按输入顺序更新
+	parallelShards(threads, func(owner int) {
+		for i := range items {
+			it := &items[i]
+			if owner == 0 && t.opt.K0 {
+				t.optimizer.UpdateW(&thetaBias.Wi, &thetaBias.WNi, &thetaBias.WZi, it.mult)
+			}
+			for j, mu := range it.theta {
+				if it.shards[j]%threads != owner {
+					continue
+				}
+				if t.opt.K1 {
+					t.optimizer.UpdateW(&mu.Wi, &mu.WNi, &mu.WZi, it.mult*it.x[j].Value)
+				}
+				for f := 0; f < k; f++ {
+					t.updateViGradient(mu, f, it.vGrad[j*k+f])
+				}
+			}
+		}
+	})
+
+	for i := range items {
+		ps[i] = items[i].p
+	}
+}
+
+// prepareSyncW 计算单元的w，与updateW相同但不加锁（调用方保证只有一个线程访问该单元）
+func (t *FTRLTrainer) prepareSyncW(mu *FTRLModelUnit) {
+	wasZero := mu.Wi == 0.0
+	t.optimizer.PrepareW(&mu.Wi, &mu.WNi, &mu.WZi)
+	if t.opt.ForceVSparse && mu.WNi > 0 && wasZero && mu.Wi != 0.0 {
+		t.model.ReinitVi(mu)
+	}
+}
+
+// parallelRange 把[0,n)均分给threads个goroutine执行fn
+func parallelRange(n, threads int, fn func(i int)) {
+	if threads > n {
+		threads = n
+	}
+	if threads <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	chunk := (n + threads - 1) / threads
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// parallelShards 启动threads个goroutine执行fn，owner为goroutine序号，
+// 负责分片序号对threads取模等于owner的特征单元
+func parallelShards(threads int, fn func(owner int)) {
+	if threads <= 1 {
+		fn(0)
+		return
+	}
+	var wg sync.WaitGroup
+	for owner := 0; owner < threads; owner++ {
+		wg.Add(1)
+		go func(owner int) {
+			defer wg.Done()
+			fn(owner)
+		}(owner)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,333 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultShardNum 特征存储的默认分片数（必须为2的幂）
+const defaultShardNum = 1024
+
+// unitShard 存储的一个分片
+// mu保护map，lock是分片内单元的更新锁：特征的创建和参数更新使用同一个分片，
+// 不同分片之间互不影响
+type unitShard[U any] struct {
+	mu    sync.RWMutex
+	lock  sync.Mutex
+	names map[string]*U
+	keys  map[uint64]*U
+	_     [64]byte // 避免相邻分片的锁位于同一缓存行
+}
+
+// unitStore 特征单元存储，按特征哈希分为多个分片
+// 默认以特征名为键；开启特征哈希后以64位哈希值为键，不保存特征名，
+// 模型文件中特征名一列写为键的十进制值。限定哈希桶数并调用useBuckets后单元存放在预分配的桶数组中，
+// 分片只用于加锁
+type unitStore[U any] struct {
+	hasher  *FeatureHasher
+	shards  []unitShard[U]
+	mask    uint64
+	admit   func(id uint64) bool // 新特征的准入判断，nil表示全部准入
+	buckets *bucketArray[U]      // 非nil时为桶数组模式
+}
+
+// newUnitStore 创建存储，hasher为nil时以特征名为键，shardNum向上取整为2的幂
+func newUnitStore[U any](hasher *FeatureHasher, shardNum int) *unitStore[U] {
+	n := 1
+	for n < shardNum {
+		n <<= 1
+	}
+	s := &unitStore[U]{
+		hasher: hasher,
+		shards: make([]unitShard[U], n),
+		mask:   uint64(n - 1),
+	}
+	for i := range s.shards {
+		if hasher != nil {
+			s.shards[i].keys = make(map[uint64]*U)
+		} else {
+			s.shards[i].names = make(map[string]*U)
+		}
+	}
+	return s
+}
+
+// useBuckets 改为桶数组模式，按hasher的桶数预分配单元，每个桶stride个向量元素；只能在存储为空时调用
+// 桶数组模式下getOrInit的newUnit不再使用，新单元由hooks.init在桶中就地初始化
+func (s *unitStore[U]) useBuckets(stride int, hooks bucketHooks[U]) {
+	s.buckets = newBucketArray[U](s.hasher.Buckets, stride, hooks)
+	for i := range s.shards {
+		s.shards[i].keys = nil
+	}
+}
+
+// nameHash 特征名的fnv-1a哈希（不分配内存），用于选择分片和按特征初始化
+func nameHash(feature string) uint64 {
+	h := uint64(fnvOffset64)
+	for i := 0; i < len(feature); i++ {
+		h ^= uint64(feature[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
+// nameShard 特征名所在的分片
+func (s *unitStore[U]) nameShard(feature string) *unitShard[U] {
+	return &s.shards[nameHash(feature)&s.mask]
+}
+
+// keyShard 哈希键所在的分片
+func (s *unitStore[U]) keyShard(key uint64) *unitShard[U] {
+	return &s.shards[key&s.mask]
+}
+
+// get 按特征名查找
+func (s *unitStore[U]) get(feature string) (*U, bool) {
+	if s.hasher != nil {
+		return s.getKey(s.hasher.Key(feature))
+	}
+	shard := s.nameShard(feature)
+	shard.mu.RLock()
+	unit, ok := shard.names[feature]
+	shard.mu.RUnlock()
+	return unit, ok
+}
+
+// getKey 按哈希键查找
+func (s *unitStore[U]) getKey(key uint64) (*U, bool) {
+	if s.buckets != nil {
+		if s.buckets.has(key) {
+			return &s.buckets.units[key], true
+		}
+		return nil, false
+	}
+	shard := s.keyShard(key)
+	shard.mu.RLock()
+	unit, ok := shard.keys[key]
+	shard.mu.RUnlock()
+	return unit, ok
+}
+
+// getOrInit 按特征名查找，不存在时用newUnit创建，同时返回单元的更新锁；未通过准入时返回nil
+func (s *unitStore[U]) getOrInit(feature string, newUnit func(id uint64) *U) (*U, *sync.Mutex) {
+	unit, idx := s.getOrInitIndex(feature, newUnit)
+	return unit, &s.shards[idx].lock
+}
+
+// getOrInitIndex 按特征名查找，不存在时用newUnit创建，同时返回单元所在分片的序号
+// newUnit的参数为特征名的哈希（特征哈希模式下为键），同一特征总是相同；
+// 特征未通过准入时返回nil
+func (s *unitStore[U]) getOrInitIndex(feature string, newUnit func(id uint64) *U) (*U, int) {
+	if s.hasher != nil {
+		key := s.hasher.Key(feature)
+		s.hasher.record(feature, key)
+		return s.getOrInitKey(key, newUnit), int(key & s.mask)
+	}
+
+	h := nameHash(feature)
+	idx := int(h & s.mask)
+	shard := &s.shards[idx]
+	shard.mu.RLock()
+	unit, exists := shard.names[feature]
+	shard.mu.RUnlock()
+	if exists {
+		return unit, idx
+	}
+	if s.admit != nil && !s.admit(h) {
+		return nil, idx
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	// 双重检查
+	if unit, exists := shard.names[feature]; exists {
+		return unit, idx
+	}
+	unit = newUnit(h)
+	shard.names[feature] = unit
+	return unit, idx
+}
+
+// getOrInitKey 按哈希键查找，不存在时用newUnit创建，未通过准入时返回nil
+func (s *unitStore[U]) getOrInitKey(key uint64, newUnit func(id uint64) *U) *U {
+	if s.buckets != nil {
+		return s.getOrInitBucket(key)
+	}
+	shard := s.keyShard(key)
+	shard.mu.RLock()
+	unit, exists := shard.keys[key]
+	shard.mu.RUnlock()
+	if exists {
+		return unit
+	}
+	if s.admit != nil && !s.admit(key) {
+		return nil
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if unit, exists := shard.keys[key]; exists {
+		return unit
+	}
+	unit = newUnit(key)
+	shard.keys[key] = unit
+	return unit
+}
+
+// getOrInitBucket 桶数组模式下按桶号查找，不存在时在桶中创建，未通过准入时返回nil
+func (s *unitStore[U]) getOrInitBucket(key uint64) *U {
+	if s.buckets.has(key) {
+		return &s.buckets.units[key]
+	}
+	if s.admit != nil && !s.admit(key) {
+		return nil
+	}
+
+	shard := s.keyShard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if s.buckets.has(key) {
+		return &s.buckets.units[key]
+	}
+	return s.buckets.create(key)
+}
+
+// shardNum 分片数
+func (s *unitStore[U]) shardNum() int {
+	return len(s.shards)
+}
+
+// set 加载模型时写入单元，name为模型文件中的特征名（哈希模式下为键的十进制值）
+func (s *unitStore[U]) set(name string, unit *U) error {
+	if s.hasher == nil {
+		shard := s.nameShard(name)
+		shard.mu.Lock()
+		shard.names[name] = unit
+		shard.mu.Unlock()
+		return nil
+	}
+	key, err := strconv.ParseUint(name, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hashed feature key: %s", name)
+	}
+	if s.buckets != nil {
+		if key >= uint64(len(s.buckets.units)) {
+			return fmt.Errorf("hashed feature key %d out of %d buckets", key, len(s.buckets.units))
+		}
+		s.buckets.set(key, unit)
+		return nil
+	}
+	shard := s.keyShard(key)
+	shard.mu.Lock()
+	shard.keys[key] = unit
+	shard.mu.Unlock()
+	return nil
+}
+
+// len 单元数
+func (s *unitStore[U]) len() int {
+	if s.buckets != nil {
+		return int(atomic.LoadInt64(&s.buckets.count))
+	}
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		n += len(shard.names) + len(shard.keys)
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// forEach 遍历所有单元，name为写入模型文件的特征名；遍历期间不能创建新单元
+func (s *unitStore[U]) forEach(fn func(name string, unit *U) error) error {
+	if s.buckets != nil {
+		return s.buckets.forEach(func(key uint64, unit *U) error {
+			return fn(strconv.FormatUint(key, 10), unit)
+		})
+	}
+	for i := range s.shards {
+		if err := s.shards[i].forEach(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (shard *unitShard[U]) forEach(fn func(name string, unit *U) error) error {
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	for key, unit := range shard.keys {
+		if err := fn(strconv.FormatUint(key, 10), unit); err != nil {
+			return err
+		}
+	}
+	for name, unit := range shard.names {
+		if err := fn(name, unit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clone 深拷贝存储，cloneUnit复制单个单元
+func (s *unitStore[U]) clone(cloneUnit func(*U) *U) *unitStore[U] {
+	c := newUnitStore[U](s.hasher, len(s.shards))
+	if s.buckets != nil {
+		c.buckets = s.buckets.clone()
+		for i := range c.shards {
+			c.shards[i].keys = nil
+		}
+		return c
+	}
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		for key, unit := range shard.keys {
+			c.shards[i].keys[key] = cloneUnit(unit)
+		}
+		for name, unit := range shard.names {
+			c.shards[i].names[name] = cloneUnit(unit)
+		}
+		shard.mu.RUnlock()
+	}
+	return c
+}
+
+// removeIf 删除fn返回true的单元，返回删除的个数和这些单元键（特征名或哈希键）占用的字节数；
+// 桶数组模式下只清空桶，不回收内存；调用时不能有并发的训练
+func (s *unitStore[U]) removeIf(fn func(unit *U) bool) (int, int64) {
+	removed, keyBytes := 0, int64(0)
+	if s.buckets != nil {
+		s.buckets.forEach(func(key uint64, unit *U) error {
+			if fn(unit) {
+				s.buckets.remove(key)
+				removed++
+			}
+			return nil
+		})
+		return removed, 0
+	}
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for key, unit := range shard.keys {
+			if fn(unit) {
+				delete(shard.keys, key)
+				removed++
+				keyBytes += 8
+			}
+		}
+		for name, unit := range shard.names {
+			if fn(unit) {
+				delete(shard.names, name)
+				removed++
+				keyBytes += int64(len(name))
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed, keyBytes
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUnitStoreConcurrentInit(t *testing.T) {
+	for _, buckets := range []int{-1, 0, 4096} {
+		var created int64
+		newUnit := func(uint64) *FTRLModelUnit {
+			atomic.AddInt64(&created, 1)
+			return NewFTRLModelUnit(2, 0, 0.1)
+		}
+		var hasher *FeatureHasher
+		if buckets >= 0 {
+			hasher = NewFeatureHasher(1, uint64(buckets))
+		}
+		s := newUnitStore[FTRLModelUnit](hasher, 16)
+		if buckets > 0 {
+			s.useBuckets(6, bucketHooks[FTRLModelUnit]{
+				bind: (*FTRLModelUnit).bindVectors,
+				init: func(uint64, *FTRLModelUnit) { atomic.AddInt64(&created, 1) },
+				copy: (*FTRLModelUnit).copyFrom,
+			})
+		}
+
+		const threads, features = 8, 1000
+		units := make([][]*FTRLModelUnit, threads)
+		locks := make([][]*sync.Mutex, threads)
+		var wg sync.WaitGroup
+		for th := 0; th < threads; th++ {
+			units[th] = make([]*FTRLModelUnit, features)
+			locks[th] = make([]*sync.Mutex, features)
+			wg.Add(1)
+			go func(th int) {
+				defer wg.Done()
+				for i := 0; i < features; i++ {
+					units[th][i], locks[th][i] = s.getOrInit("f"+strconv.Itoa(i), newUnit)
+				}
+			}(th)
+		}
+		wg.Wait()
+
+		// 每个特征（限定桶数时为每个桶）只创建一次，所有线程拿到同一个单元和同一把锁
+		want := features
+		if buckets > 0 {
+			keys := make(map[uint64]bool)
+			for i := 0; i < features; i++ {
+				keys[hasher.Key("f"+strconv.Itoa(i))] = true
+			}
+			want = len(keys)
+		}
+		if created != int64(want) || s.len() != want {
+			t.Fatalf("buckets=%d: created %d units, len %d, want %d", buckets, created, s.len(), want)
+		}
+		for th := 1; th < threads; th++ {
+			for i := 0; i < features; i++ {
+				if units[th][i] != units[0][i] || locks[th][i] != locks[0][i] {
+					t.Fatalf("buckets=%d: feature %d got different unit or lock", buckets, i)
+				}
+			}
+		}
+
+		c := s.clone((*FTRLModelUnit).Clone)
+		n := 0
+		if err := c.forEach(func(name string, unit *FTRLModelUnit) error {
+			n++
+			if orig, ok := s.get("f0"); ok && unit == orig {
+				return fmt.Errorf("clone shares unit %s", name)
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("buckets=%d: clone has %d units, want %d", buckets, n, want)
+		}
+	}
+}
+
+// BenchmarkUnitStore 模拟训练时的访问：查找或创建特征单元后持锁更新
+// shards=1 相当于全局锁，对比不同线程数下分片存储的扩展性
+func BenchmarkUnitStore(b *testing.B) {
+	const featureNum = 1 << 20
+	features := make([]string, featureNum)
+	for i := range features {
+		features[i] = "fea_" + strconv.Itoa(i)
+	}
+
+	for _, shards := range []int{1, defaultShardNum} {
+		for _, threads := range []int{1, 2, 4, 8, 16, 32} {
+			b.Run(fmt.Sprintf("shards=%d/threads=%d", shards, threads), func(b *testing.B) {
+				s := newUnitStore[FTRLModelUnit](nil, shards)
+				newUnit := func(uint64) *FTRLModelUnit { return NewFTRLModelUnit(8, 0, 0.1) }
+				var next int64
+				var wg sync.WaitGroup
+				b.ResetTimer()
+				for th := 0; th < threads; th++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							i := atomic.AddInt64(&next, 1) - 1
+							if i >= int64(b.N) {
+								return
+							}
+							unit, lock := s.getOrInit(features[(i*7919)%featureNum], newUnit)
+							lock.Lock()
+							unit.WNi += 1.0
+							unit.WZi += 0.5
+							lock.Unlock()
+						}
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
+
+// BenchmarkHashBucketMemory 限定哈希桶数时分片map与桶数组两种存储的堆内存，按占用的桶数平均
+// 特征数为桶数的4倍，约98%的桶被占用；桶数组按全部桶数分配，未占用的桶也计入
+func BenchmarkHashBucketMemory(b *testing.B) {
+	const buckets = 1 << 18
+	features := make([]string, 4*buckets)
+	for i := range features {
+		features[i] = "fea_" + strconv.Itoa(i)
+	}
+	for _, flat := range []bool{false, true} {
+		b.Run(fmt.Sprintf("buckets=%v", flat), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				m := NewFTRLModel(8, 0, 0.1)
+				meta := NewModelMeta()
+				meta.Hashed, meta.HashBuckets = true, buckets
+				m.SetMeta(meta)
+				if !flat {
+					m.store = newUnitStore[FTRLModelUnit](m.Hasher(), defaultShardNum)
+				}
+				for _, f := range features {
+					m.GetOrInitModelUnit(f)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(m.FeatureNum()), "B/feature")
+				runtime.KeepAlive(m)
+			}
+		})
+	}
+}
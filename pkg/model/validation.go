@@ -0,0 +1,193 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xiongle/alphaFM-go/pkg/metrics"
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+)
+
+// 早停使用的验证指标
+const (
+	ValMetricLogLoss = "logloss"
+	ValMetricAUC     = "auc"
+	ValMetricMSE     = "mse"
+)
+
+// IsValidValMetric 判断验证指标对训练目标是否可用，非logistic目标只能使用mse
+func IsValidValMetric(metric, loss string) bool {
+	switch metric {
+	case ValMetricLogLoss, ValMetricAUC:
+		return loss == LossLogistic
+	case ValMetricMSE:
+		return true
+	}
+	return false
+}
+
+// ValidationResult 一次验证的结果
+type ValidationResult struct {
+	Summary  *metrics.Summary
+	Score    float64 // 早停使用的指标值
+	Best     float64 // 目前最好的指标值
+	Improved bool    // 本次是否刷新了最好结果
+	Stop     bool    // 连续patience次没有提升，应当停止训练
+}
+
+// validationState 验证集和早停状态
+type validationState struct {
+	samples   []*sample.FMSample
+	best      *FTRLModel // 最好的模型快照
+	bestScore float64
+	noImprove int
+	last      *ValidationResult
+	trained   int64 // 上次验证以来训练的样本数
+}
+
+// LoadValidationSet 加载验证集，之后可以调用Validate
+func (t *FTRLTrainer) LoadValidationSet(path string) error {
+	if !IsValidValMetric(t.opt.ValMetric, t.loss.Name()) {
+		return fmt.Errorf("invalid validation metric for %s loss: %s", t.loss.Name(), t.opt.ValMetric)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open validation file error: %v", err)
+	}
+	defer f.Close()
+
+	isFFM := t.opt.ModelType == ModelTypeFFM
+	var samples []*sample.FMSample
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var s *sample.FMSample
+		if isFFM {
+			s, err = sample.ParseFFMSample(scanner.Text(), t.opt.FieldNum, t.labelKind)
+		} else {
+			s, err = sample.ParseSample(scanner.Text(), t.labelKind)
+		}
+		if err != nil {
+			fmt.Printf("Warning: skip invalid validation sample: %v\n", err)
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read validation file error: %v", err)
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("empty validation file: %s", path)
+	}
+
+	t.val = &validationState{samples: samples}
+	return nil
+}
+
+// Validate 用当前模型为验证集打分，保存最好的模型快照并判断是否早停
+// 调用时不能有并发的训练（在PCFrame暂停点或一轮训练结束后调用）
+// 上次验证以来没有训练新样本时直接返回上次的结果
+func (t *FTRLTrainer) Validate() *ValidationResult {
+	v := t.val
+	if v.last != nil && atomic.LoadInt64(&v.trained) == 0 {
+		return v.last
+	}
+	atomic.StoreInt64(&v.trained, 0)
+
+	summary := t.evaluate(v.samples)
+	r := &ValidationResult{Summary: summary}
+	switch t.opt.ValMetric {
+	case ValMetricAUC:
+		r.Score = summary.AUC
+		r.Improved = v.best == nil || r.Score > v.bestScore
+	case ValMetricMSE:
+		r.Score = summary.MSE
+		r.Improved = v.best == nil || r.Score < v.bestScore
+	default:
+		r.Score = summary.LogLoss
+		r.Improved = v.best == nil || r.Score < v.bestScore
+	}
+
+	if r.Improved {
+		v.best = t.Snapshot()
+		v.bestScore = r.Score
+		v.noImprove = 0
+	} else {
+		v.noImprove++
+	}
+	r.Best = v.bestScore
+	r.Stop = t.opt.ValPatience > 0 && v.noImprove >= t.opt.ValPatience
+	v.last = r
+	return r
+}
+
+// evaluate 多线程为样本打分并计算评估指标，模型中不存在的特征视为缺失
+func (t *FTRLTrainer) evaluate(samples []*sample.FMSample) *metrics.Summary {
+	evalOpt := metrics.NewEvaluatorOption()
+	evalOpt.Regression = t.loss.Name() != LossLogistic
+	threadNum := t.opt.ThreadsNum
+	if threadNum < 1 {
+		threadNum = 1
+	}
+	chunk := (len(samples) + threadNum - 1) / threadNum
+
+	total := metrics.NewEvaluator(evalOpt)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for start := 0; start < len(samples); start += chunk {
+		end := start + chunk
+		if end > len(samples) {
+			end = len(samples)
+		}
+		wg.Add(1)
+		go func(part []*sample.FMSample) {
+			defer wg.Done()
+			e := metrics.NewEvaluator(evalOpt)
+			for _, s := range part {
+				e.Add(t.predictValue(s.X), t.evalLabel(s), s.W)
+			}
+			mu.Lock()
+			total.Merge(e)
+			mu.Unlock()
+		}(samples[start:end])
+	}
+	wg.Wait()
+	return total.Summary()
+}
+
+// evalLabel 评估使用的标签，logistic目标为0/1，其他目标为原始标签
+func (t *FTRLTrainer) evalLabel(s *sample.FMSample) float64 {
+	if t.loss.Name() == LossLogistic {
+		return binaryLabel(s.Y)
+	}
+	return s.Label
+}
+
+// predictValue 只读地计算预测值（经过link函数），不创建新的模型单元
+func (t *FTRLTrainer) predictValue(x []sample.FeatureValue) float64 {
+	bias := 0.0
+	if t.model.MuBias != nil {
+		bias = t.model.MuBias.Wi
+	}
+
+	known := make([]sample.FeatureValue, 0, len(x))
+	theta := make([]*FTRLModelUnit, 0, len(x))
+	for i := range x {
+		if unit, ok := t.model.GetModelUnit(x[i].Feature); ok {
+			known = append(known, x[i])
+			theta = append(theta, unit)
+		}
+	}
+
+	var p float64
+	if t.opt.ModelType == ModelTypeFFM {
+		p = predictFFM(known, bias, theta, t.model.FactorNum)
+	} else {
+		p = t.predictScalar(known, bias, theta)
+	}
+	return t.loss.Link(p)
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidationKeepsBestSnapshot(t *testing.T) {
+	rand.Seed(1)
+	r := rand.New(rand.NewSource(1))
+	dir := t.TempDir()
+
+	valPath := filepath.Join(dir, "val.txt")
+	if err := os.WriteFile(valPath, []byte(strings.Join(genFMLines(200, r), "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := NewTrainerOption()
+	opt.FactorNum = 2
+	opt.ValMetric = ValMetricAUC
+	opt.ValPatience = 1
+	trainer := NewFTRLTrainer(opt)
+	if err := trainer.LoadValidationSet(valPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trainer.RunTask(genFMLines(2000, r)); err != nil {
+		t.Fatal(err)
+	}
+	first := trainer.Validate()
+	if !first.Improved || first.Stop || first.Summary.AUC < 0.99 {
+		t.Fatalf("first validation = %+v, auc = %v", first, first.Summary.AUC)
+	}
+	if again := trainer.Validate(); again != first {
+		t.Fatal("validation without new training should return the last result")
+	}
+
+	// 标签反转的样本使模型变差，patience为1时应当早停
+	var flipped []string
+	for _, line := range genFMLines(4000, r) {
+		if line[0] == '1' {
+			flipped = append(flipped, "0"+line[1:])
+		} else {
+			flipped = append(flipped, "1"+line[1:])
+		}
+	}
+	if err := trainer.RunTask(flipped); err != nil {
+		t.Fatal(err)
+	}
+	second := trainer.Validate()
+	if second.Improved || !second.Stop || second.Best != first.Score {
+		t.Fatalf("second validation = %+v", second)
+	}
+
+	// OutputBestModel输出最好的快照，OutputModel输出当前的模型
+	bestPath := filepath.Join(dir, "best.txt")
+	if score, err := trainer.OutputBestModel(bestPath, "txt"); err != nil || score != first.Score {
+		t.Fatalf("best model score %v, error %v", score, err)
+	}
+	lastPath := filepath.Join(dir, "last.txt")
+	if err := trainer.OutputModel(lastPath, "txt"); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]float64{bestPath: first.Summary.AUC, lastPath: second.Summary.AUC} {
+		check := NewTrainerOption()
+		check.FactorNum = 2
+		checker := NewFTRLTrainer(check)
+		if err := checker.LoadModel(path, "txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err := checker.LoadValidationSet(valPath); err != nil {
+			t.Fatal(err)
+		}
+		if auc := checker.Validate().Summary.AUC; auc != want {
+			t.Fatalf("%s: auc = %v, want %v", path, auc, want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package sample
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+)
+
+// SampleCache 解析后样本的缓存，用于多轮训练时回放
+// 样本先保存在内存中，估算占用超过memLimit后全部溢写到二进制文件，之后的样本直接追加到文件
+type SampleCache struct {
+	mu        sync.Mutex
+	memLimit  int64
+	memBytes  int64
+	samples   []*FMSample
+	spillPath string
+	spillFile *os.File
+	writer    *bufio.Writer
+	offsets   []int64 // 溢写模式下每条样本在文件中的起始偏移，最后一个元素为文件末尾
+	count     int
+	buf       []byte
+}
+
+// NewSampleCache 创建样本缓存
+// spillPath为空时在系统临时目录下创建溢写文件
+func NewSampleCache(memLimit int64, spillPath string) *SampleCache {
+	return &SampleCache{
+		memLimit:  memLimit,
+		spillPath: spillPath,
+		offsets:   []int64{0},
+	}
+}
+
+// sampleMemSize 估算样本的内存占用
+func sampleMemSize(s *FMSample) int64 {
+	size := int64(80) + int64(len(s.Group))
+	for i := range s.X {
+		size += int64(len(s.X[i].Feature)) + 48
+	}
+	return size
+}
+
+// AddBatch 追加一批样本（并发安全，同一批样本保持连续）
+func (c *SampleCache) AddBatch(batch []*FMSample) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range batch {
+		if c.spillFile == nil {
+			c.samples = append(c.samples, s)
+			c.memBytes += sampleMemSize(s)
+			c.count++
+			if c.memBytes > c.memLimit {
+				if err := c.spillLocked(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := c.writeLocked(s); err != nil {
+			return err
+		}
+		c.count++
+	}
+	return nil
+}
+
+// spillLocked 把内存中的样本全部写入溢写文件
+func (c *SampleCache) spillLocked() error {
+	var f *os.File
+	var err error
+	if c.spillPath != "" {
+		f, err = os.Create(c.spillPath)
+	} else {
+		f, err = os.CreateTemp("", "fm_sample_cache_*.bin")
+	}
+	if err != nil {
+		return fmt.Errorf("create sample cache file error: %v", err)
+	}
+	c.spillFile = f
+	c.spillPath = f.Name()
+	c.writer = bufio.NewWriterSize(f, 1<<20)
+
+	for _, s := range c.samples {
+		if err := c.writeLocked(s); err != nil {
+			return err
+		}
+	}
+	c.samples = nil
+	c.memBytes = 0
+	fmt.Printf("sample cache exceeds memory limit, spilled to %s\n", c.spillPath)
+	return nil
+}
+
+// writeLocked 编码一条样本并写入溢写文件
+// 格式: label(float64) + weight(float64) + group_len(uvarint) + group + feature_num(uvarint) + [field(uvarint) + name_len(uvarint) + name + value(float64)]...
+func (c *SampleCache) writeLocked(s *FMSample) error {
+	c.buf = encodeSample(c.buf[:0], s)
+	if _, err := c.writer.Write(c.buf); err != nil {
+		return err
+	}
+	c.offsets = append(c.offsets, c.offsets[len(c.offsets)-1]+int64(len(c.buf)))
+	return nil
+}
+
+func encodeSample(buf []byte, s *FMSample) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	binary.LittleEndian.PutUint64(tmp[:8], math.Float64bits(s.Label))
+	buf = append(buf, tmp[:8]...)
+	binary.LittleEndian.PutUint64(tmp[:8], math.Float64bits(s.W))
+	buf = append(buf, tmp[:8]...)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(s.Group)))]...)
+	buf = append(buf, s.Group...)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(s.X)))]...)
+	for i := range s.X {
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(s.X[i].Field))]...)
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(s.X[i].Feature)))]...)
+		buf = append(buf, s.X[i].Feature...)
+		binary.LittleEndian.PutUint64(tmp[:8], math.Float64bits(s.X[i].Value))
+		buf = append(buf, tmp[:8]...)
+	}
+	return buf
+}
+
+func decodeSample(r io.ByteReader, read func(p []byte) error) (*FMSample, error) {
+	var valueBytes [8]byte
+	if err := read(valueBytes[:]); err != nil {
+		return nil, err
+	}
+	label := math.Float64frombits(binary.LittleEndian.Uint64(valueBytes[:]))
+	if err := read(valueBytes[:]); err != nil {
+		return nil, err
+	}
+	weight := math.Float64frombits(binary.LittleEndian.Uint64(valueBytes[:]))
+	groupLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	group := make([]byte, groupLen)
+	if err := read(group); err != nil {
+		return nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	s := &FMSample{Y: -1, Label: label, W: weight, X: make([]FeatureValue, n), Group: string(group)}
+	if label > 0 {
+		s.Y = 1
+	}
+	for i := range s.X {
+		field, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		nameLen, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, nameLen)
+		if err := read(name); err != nil {
+			return nil, err
+		}
+		if err := read(valueBytes[:]); err != nil {
+			return nil, err
+		}
+		s.X[i] = FeatureValue{
+			Field:   int(field),
+			Feature: string(name),
+			Value:   math.Float64frombits(binary.LittleEndian.Uint64(valueBytes[:])),
+		}
+	}
+	return s, nil
+}
+
+// Len 缓存的样本数
+func (c *SampleCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+// IsSpilled 是否已溢写到文件
+func (c *SampleCache) IsSpilled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spillFile != nil
+}
+
+// Finish 写入结束，刷新溢写文件缓冲
+func (c *SampleCache) Finish() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writer != nil {
+		return c.writer.Flush()
+	}
+	return nil
+}
+
+// ShuffledOrder 使用给定随机数生成器生成打乱的回放顺序
+func (c *SampleCache) ShuffledOrder(r *rand.Rand) []int {
+	return r.Perm(c.Len())
+}
+
+// Batches 按order的顺序（为nil时按写入顺序）分批回放样本，每批最多batchSize条
+// 必须在Finish之后调用，回放期间不能再追加样本
+func (c *SampleCache) Batches(order []int, batchSize int, fn func(batch []*FMSample) error) error {
+	batch := make([]*FMSample, 0, batchSize)
+	emit := func(s *FMSample) error {
+		batch = append(batch, s)
+		if len(batch) >= batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]*FMSample, 0, batchSize)
+		}
+		return nil
+	}
+
+	switch {
+	case c.spillFile == nil && order == nil:
+		for _, s := range c.samples {
+			if err := emit(s); err != nil {
+				return err
+			}
+		}
+	case c.spillFile == nil:
+		for _, idx := range order {
+			if err := emit(c.samples[idx]); err != nil {
+				return err
+			}
+		}
+	case order == nil:
+		// 顺序读取溢写文件
+		r := bufio.NewReaderSize(io.NewSectionReader(c.spillFile, 0, c.offsets[len(c.offsets)-1]), 1<<20)
+		read := func(p []byte) error {
+			_, err := io.ReadFull(r, p)
+			return err
+		}
+		for i := 0; i < c.count; i++ {
+			s, err := decodeSample(r, read)
+			if err != nil {
+				return fmt.Errorf("read sample cache error: %v", err)
+			}
+			if err := emit(s); err != nil {
+				return err
+			}
+		}
+	default:
+		// 按偏移随机读取溢写文件
+		var record []byte
+		for _, idx := range order {
+			start, end := c.offsets[idx], c.offsets[idx+1]
+			if cap(record) < int(end-start) {
+				record = make([]byte, end-start)
+			}
+			record = record[:end-start]
+			if _, err := c.spillFile.ReadAt(record, start); err != nil {
+				return fmt.Errorf("read sample cache error: %v", err)
+			}
+			s, err := decodeRecord(record)
+			if err != nil {
+				return fmt.Errorf("read sample cache error: %v", err)
+			}
+			if err := emit(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// byteSliceReader 从内存记录中解码
+type byteSliceReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteSliceReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.EOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *byteSliceReader) read(p []byte) error {
+	if r.pos+len(p) > len(r.data) {
+		return io.ErrUnexpectedEOF
+	}
+	copy(p, r.data[r.pos:])
+	r.pos += len(p)
+	return nil
+}
+
+func decodeRecord(record []byte) (*FMSample, error) {
+	r := &byteSliceReader{data: record}
+	return decodeSample(r, r.read)
+}
+
+// Close 释放缓存并删除溢写文件
+func (c *SampleCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = nil
+	if c.spillFile == nil {
+		return nil
+	}
+	c.spillFile.Close()
+	c.spillFile = nil
+	return os.Remove(c.spillPath)
+}
@@ -0,0 +1,66 @@
+package sample
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func collect(t *testing.T, c *SampleCache, order []int) []*FMSample {
+	var out []*FMSample
+	if err := c.Batches(order, 3, func(batch []*FMSample) error {
+		if len(batch) > 3 {
+			t.Fatalf("batch too large: %d", len(batch))
+		}
+		out = append(out, batch...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestSampleCacheReplay(t *testing.T) {
+	var samples []*FMSample
+	for i := 0; i < 10; i++ {
+		s, err := ParseFFMSample(fmt.Sprintf("%g:%d 0:u%d:1 1:i%d:0.5", float64(i%4)*0.75, i%3+1, i, i*7), 2, LabelReal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Group = fmt.Sprintf("q%d", i/3)
+		samples = append(samples, s)
+	}
+
+	for _, memLimit := range []int64{1 << 20, 0} {
+		c := NewSampleCache(memLimit, "")
+		if err := c.AddBatch(samples[:4]); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddBatch(samples[4:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		if c.Len() != len(samples) || c.IsSpilled() != (memLimit == 0) {
+			t.Fatalf("unexpected cache state: len=%d spilled=%v", c.Len(), c.IsSpilled())
+		}
+
+		if got := collect(t, c, nil); !reflect.DeepEqual(got, samples) {
+			t.Errorf("sequential replay mismatch (memLimit=%d)", memLimit)
+		}
+
+		order := c.ShuffledOrder(rand.New(rand.NewSource(1)))
+		got := collect(t, c, order)
+		for i, idx := range order {
+			if !reflect.DeepEqual(got[i], samples[idx]) {
+				t.Errorf("shuffled replay mismatch at %d (memLimit=%d)", i, memLimit)
+			}
+		}
+
+		if err := c.Close(); err != nil {
+			t.Errorf("close failed: %v", err)
+		}
+	}
+}
@@ -2,24 +2,66 @@ package sample
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
 // FMSample 样本数据结构
 type FMSample struct {
-	Y int                         // 标签: 1 或 -1
-	X []FeatureValue              // 特征列表
+	Y     int            // 标签: 1 或 -1
+	Label float64        // 原始标签值，回归和计数目标使用
+	W     float64        // 样本权重，默认为1
+	X     []FeatureValue // 特征列表
+	Group string         // 分组键，成对排序训练时使用
 }
 
 // FeatureValue 特征和值
 type FeatureValue struct {
+	Field   int // FFM的field编号，FM样本为0
 	Feature string
 	Value   float64
 }
 
+// LabelKind 标签的取值范围，由训练目标决定
+type LabelKind int
+
+const (
+	LabelBinary      LabelKind = iota // 二分类：整数0/1或-1/1
+	LabelInteger                      // 任意整数：多分类的类别号、排序的相关度等级
+	LabelReal                         // 实数：squared回归目标
+	LabelNonNegative                  // 非负实数：poisson计数目标
+)
+
+// parseLabel 解析标签列，格式为label或label:weight，标签按kind校验，权重必须非负
+func parseLabel(s string, kind LabelKind) (float64, float64, error) {
+	weight := 1.0
+	if idx := strings.IndexByte(s, ':'); idx >= 0 {
+		w, err := strconv.ParseFloat(s[idx+1:], 64)
+		if err != nil || w < 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			return 0, 0, fmt.Errorf("invalid sample weight: %s", s)
+		}
+		weight = w
+		s = s[:idx]
+	}
+	switch kind {
+	case LabelBinary, LabelInteger:
+		label, err := strconv.Atoi(s)
+		if err != nil || (kind == LabelBinary && (label < -1 || label > 1)) {
+			return 0, 0, fmt.Errorf("invalid label: %s", s)
+		}
+		return float64(label), weight, nil
+	}
+	label, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(label, 0) || math.IsNaN(label) || (kind == LabelNonNegative && label < 0) {
+		return 0, 0, fmt.Errorf("invalid label: %s", s)
+	}
+	return label, weight, nil
+}
+
 // ParseSample 解析样本字符串
-func ParseSample(line string) (*FMSample, error) {
+// 格式: label[:weight] feature1:value1 ...，标签按kind校验
+func ParseSample(line string, kind LabelKind) (*FMSample, error) {
 	parts := strings.Fields(line)
 	if len(parts) == 0 {
 		return nil, fmt.Errorf("empty line")
@@ -30,15 +72,17 @@ func ParseSample(line string) (*FMSample, error) {
 	}
 
 	// 解析标签
-	label, err := strconv.Atoi(parts[0])
+	label, weight, err := parseLabel(parts[0], kind)
 	if err != nil {
-		return nil, fmt.Errorf("invalid label: %v", err)
+		return nil, err
 	}
 	if label > 0 {
 		sample.Y = 1
 	} else {
 		sample.Y = -1
 	}
+	sample.Label = label
+	sample.W = weight
 
 	// 解析特征
 	for i := 1; i < len(parts); i++ {
@@ -69,3 +113,83 @@ func ParseSample(line string) (*FMSample, error) {
 	return sample, nil
 }
 
+// ParseFFMSample 解析FFM样本字符串
+// 格式: label[:weight] field:feature:value ...，field为[0, fieldNum)内的整数，标签按kind校验
+func ParseFFMSample(line string, fieldNum int, kind LabelKind) (*FMSample, error) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	sample := &FMSample{
+		X: make([]FeatureValue, 0, len(parts)-1),
+	}
+
+	// 解析标签
+	label, weight, err := parseLabel(parts[0], kind)
+	if err != nil {
+		return nil, err
+	}
+	if label > 0 {
+		sample.Y = 1
+	} else {
+		sample.Y = -1
+	}
+	sample.Label = label
+	sample.W = weight
+
+	// 解析特征
+	for i := 1; i < len(parts); i++ {
+		kv := strings.Split(parts[i], ":")
+		if len(kv) != 3 {
+			return nil, fmt.Errorf("invalid ffm feature format: %s", parts[i])
+		}
+
+		field, err := strconv.Atoi(kv[0])
+		if err != nil || field < 0 || field >= fieldNum {
+			return nil, fmt.Errorf("invalid field: %s", parts[i])
+		}
+
+		// 跳过空值
+		if kv[2] == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(kv[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature value: %v", err)
+		}
+
+		// 跳过值为0的特征
+		if value != 0 {
+			sample.X = append(sample.X, FeatureValue{
+				Field:   field,
+				Feature: kv[1],
+				Value:   value,
+			})
+		}
+	}
+
+	return sample, nil
+}
+
+// SplitGroupColumn 拆出行首的分组键列，返回分组键和剩余的样本行
+// 格式: group label feature1:value1 ...
+func SplitGroupColumn(line string) (string, string, error) {
+	line = strings.TrimLeft(line, " \t")
+	idx := strings.IndexAny(line, " \t")
+	if idx <= 0 {
+		return "", "", fmt.Errorf("missing group column")
+	}
+	return line[:idx], line[idx+1:], nil
+}
+
+// GroupKeyByPrefix 返回第一个以prefix开头的特征名作为分组键，没有时返回空串
+func GroupKeyByPrefix(x []FeatureValue, prefix string) string {
+	for i := range x {
+		if strings.HasPrefix(x[i].Feature, prefix) {
+			return x[i].Feature
+		}
+	}
+	return ""
+}
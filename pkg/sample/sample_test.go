@@ -0,0 +1,91 @@
+package sample
+
+import "testing"
+
+func TestParseSampleWeight(t *testing.T) {
+	s, err := ParseSample("1:2.5 a:1 b:0.5", LabelBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != 1 || s.W != 2.5 || len(s.X) != 2 {
+		t.Fatalf("sample = %+v", s)
+	}
+
+	s, err = ParseSample("0 a:1", LabelBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != -1 || s.W != 1 {
+		t.Fatalf("default weight: sample = %+v", s)
+	}
+
+	s, err = ParseFFMSample("0:3 1:a:1", 2, LabelBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != -1 || s.W != 3 {
+		t.Fatalf("ffm sample = %+v", s)
+	}
+
+	for _, line := range []string{"1:-1 a:1", "1:x a:1", "1: a:1"} {
+		if _, err := ParseSample(line, LabelBinary); err == nil {
+			t.Errorf("%q should be invalid", line)
+		}
+	}
+}
+
+func TestParseSampleRealLabel(t *testing.T) {
+	s, err := ParseSample("12.5:2 a:1", LabelReal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Label != 12.5 || s.Y != 1 || s.W != 2 {
+		t.Fatalf("sample = %+v", s)
+	}
+
+	s, err = ParseSample("-1 a:1", LabelReal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Label != -1 || s.Y != -1 {
+		t.Fatalf("sample = %+v", s)
+	}
+
+	for _, line := range []string{"NaN a:1", "inf a:1", "x a:1"} {
+		if _, err := ParseSample(line, LabelReal); err == nil {
+			t.Errorf("%q should be invalid", line)
+		}
+	}
+}
+
+func TestParseSampleLabelKind(t *testing.T) {
+	cases := []struct {
+		label string
+		kind  LabelKind
+		valid bool
+	}{
+		{"1", LabelBinary, true},
+		{"-1", LabelBinary, true},
+		{"0", LabelBinary, true},
+		{"0.5", LabelBinary, false},
+		{"1e-3", LabelBinary, false},
+		{"1.0", LabelBinary, false},
+		{"2", LabelBinary, false},
+		{"3", LabelInteger, true},
+		{"0.5", LabelInteger, false},
+		{"0.5", LabelReal, true},
+		{"-2.5", LabelReal, true},
+		{"1e-3", LabelNonNegative, true},
+		{"-1", LabelNonNegative, false},
+	}
+	for _, c := range cases {
+		line := c.label + " a:1"
+		if _, err := ParseSample(line, c.kind); (err == nil) != c.valid {
+			t.Errorf("ParseSample(%q, %d): err = %v, want valid = %v", line, c.kind, err, c.valid)
+		}
+		line = c.label + " 0:a:1"
+		if _, err := ParseFFMSample(line, 1, c.kind); (err == nil) != c.valid {
+			t.Errorf("ParseFFMSample(%q, %d): err = %v, want valid = %v", line, c.kind, err, c.valid)
+		}
+	}
+}
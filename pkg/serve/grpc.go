@@ -0,0 +1,98 @@
+package serve
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/xiongle/alphaFM-go/pkg/serve/pb"
+)
+
+// grpcService gRPC打分服务，与HTTP接口共用模型、并发限制和样本转换
+type grpcService struct {
+	pb.UnimplementedPredictorServer
+	s *Server
+}
+
+// NewGRPCServer 创建注册了Predictor服务的gRPC服务器，单个消息的大小上限为MaxBodyBytes
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.MaxRecvMsgSize(int(s.opt.MaxBodyBytes))}, opts...)
+	g := grpc.NewServer(opts...)
+	pb.RegisterPredictorServer(g, &grpcService{s: s})
+	return g
+}
+
+// Predict 对一个请求打分，等待名额超过QueueTimeout时返回ResourceExhausted
+func (g *grpcService) Predict(ctx context.Context, req *pb.PredictRequest) (*pb.PredictResponse, error) {
+	if !g.s.acquire() {
+		return nil, status.Error(codes.ResourceExhausted, "too many concurrent requests")
+	}
+	defer g.s.release()
+	return g.predict(req)
+}
+
+// BatchPredict 依次处理流中的请求
+// 每个请求阻塞等待空闲名额而不是返回ResourceExhausted，由gRPC流控向客户端形成背压
+func (g *grpcService) BatchPredict(stream pb.Predictor_BatchPredictServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case g.s.sem <- struct{}{}:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		resp, err := g.predict(req)
+		g.s.release()
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// GetModelInfo 当前模型的版本、元信息和热更新状态
+func (g *grpcService) GetModelInfo(ctx context.Context, req *pb.ModelInfoRequest) (*pb.ModelInfo, error) {
+	st := g.s.holder.Status()
+	return &pb.ModelInfo{
+		Version:        st.Version,
+		Path:           st.Path,
+		Format:         st.Format,
+		LoadedAtUnixMs: st.LoadedAt.UnixNano() / 1e6,
+		FactorNum:      int32(st.FactorNum),
+		Features:       int64(st.Features),
+		Meta:           st.Meta,
+		Reloads:        st.Reloads,
+		ReloadFailures: st.ReloadFailures,
+		LastError:      st.LastError,
+	}, nil
+}
+
+func (g *grpcService) predict(req *pb.PredictRequest) (*pb.PredictResponse, error) {
+	switch {
+	case len(req.Samples) == 0:
+		return nil, status.Errorf(codes.InvalidArgument, "request %q: no samples", req.RequestId)
+	case len(req.Samples) > g.s.opt.MaxBatch:
+		return nil, status.Errorf(codes.ResourceExhausted, "request %q: %d samples exceed the limit %d", req.RequestId, len(req.Samples), g.s.opt.MaxBatch)
+	}
+	samples := make([]Sample, len(req.Samples))
+	for i, smp := range req.Samples {
+		samples[i] = Sample{Features: smp.Features, Line: smp.Line}
+	}
+	lm, scores, err := g.s.scoreSamples(samples)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "request %q: %v", req.RequestId, err)
+	}
+	return &pb.PredictResponse{Scores: scores, ModelVersion: lm.Version, RequestId: req.RequestId}, nil
+}
@@ -0,0 +1,135 @@
+package serve
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/xiongle/alphaFM-go/pkg/serve/pb"
+)
+
+// dialGRPC 在bufconn上启动gRPC服务并返回客户端
+func dialGRPC(t *testing.T, server *Server) pb.PredictorClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := server.NewGRPCServer()
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewPredictorClient(conn)
+}
+
+func TestGRPCPredict(t *testing.T) {
+	holder := newHolder(t)
+	opt := NewOption()
+	opt.MaxBatch = 3
+	server, err := NewServer(holder, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dialGRPC(t, server)
+	ctx := context.Background()
+	m := holder.Current().Model
+	want := m.GetScore([]scoreFeature{{"a", 1}, {"b", 1}}, m.MuBias.Wi)
+
+	resp, err := client.Predict(ctx, &pb.PredictRequest{
+		RequestId: "r1",
+		Samples: []*pb.Sample{
+			{Features: map[string]float64{"b": 1, "a": 1}},
+			{Line: "0 a:1 b:1 unknown:1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Scores) != 2 || resp.Scores[0] != resp.Scores[1] || math.Abs(resp.Scores[0]-want) > 1e-12 {
+		t.Fatalf("scores = %v, want %v", resp.Scores, want)
+	}
+	if resp.RequestId != "r1" || resp.ModelVersion != holder.Current().Version {
+		t.Fatalf("response = %v", resp)
+	}
+
+	for _, tc := range []struct {
+		req  *pb.PredictRequest
+		code codes.Code
+	}{
+		{&pb.PredictRequest{}, codes.InvalidArgument},
+		{&pb.PredictRequest{Samples: []*pb.Sample{{Line: "x a:1"}}}, codes.InvalidArgument},
+		{&pb.PredictRequest{Samples: []*pb.Sample{{}}}, codes.InvalidArgument},
+		{&pb.PredictRequest{Samples: make([]*pb.Sample, 4)}, codes.ResourceExhausted},
+	} {
+		if _, err := client.Predict(ctx, tc.req); status.Code(err) != tc.code {
+			t.Errorf("%v: error %v, want %v", tc.req, err, tc.code)
+		}
+	}
+
+	info, err := client.GetModelInfo(ctx, &pb.ModelInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != holder.Current().Version || info.FactorNum != 2 || info.Features != 3 || info.Meta != "model_type=fm" || info.Format != "txt" {
+		t.Fatalf("model info = %v", info)
+	}
+}
+
+func TestGRPCBatchPredict(t *testing.T) {
+	opt := NewOption()
+	opt.MaxConcurrent = 1
+	server, err := NewServer(newHolder(t), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dialGRPC(t, server)
+
+	stream, err := client.BatchPredict(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{"a", "b", "c"}
+	go func() {
+		for _, id := range ids {
+			stream.Send(&pb.PredictRequest{RequestId: id, Samples: []*pb.Sample{{Line: "1 a:1"}, {Line: "1 " + id + ":1"}}})
+		}
+		stream.CloseSend()
+	}()
+	for _, id := range ids {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.RequestId != id || len(resp.Scores) != 2 {
+			t.Fatalf("response = %v, want request %s", resp, id)
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected end of stream, got %v", err)
+	}
+
+	// 出错的请求以错误状态结束整个流
+	stream, err = client.BatchPredict(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&pb.PredictRequest{RequestId: "ok", Samples: []*pb.Sample{{Line: "1 a:1"}}})
+	stream.Send(&pb.PredictRequest{RequestId: "bad", Samples: []*pb.Sample{{Line: "x a:1"}}})
+	if resp, err := stream.Recv(); err != nil || resp.RequestId != "ok" {
+		t.Fatalf("first response: %v %v", resp, err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
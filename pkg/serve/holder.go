@@ -0,0 +1,211 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiongle/alphaFM-go/pkg/model"
+)
+
+// LoadedModel 已加载的模型，加载后只读，可被多个请求同时使用
+type LoadedModel struct {
+	Model    *model.PredictModel
+	Version  string    // 模型版本，由模型文件的修改时间和大小生成
+	LoadedAt time.Time // 加载完成的时间
+	modTime  time.Time
+	size     int64
+}
+
+// HolderStatus 模型热更新的状态
+type HolderStatus struct {
+	Path           string    `json:"path"`
+	Format         string    `json:"format"`
+	Version        string    `json:"version"`
+	LoadedAt       time.Time `json:"loaded_at"`
+	FactorNum      int       `json:"factor_num"`
+	Features       int       `json:"features"`
+	Meta           string    `json:"meta"` // 模型元信息，如 model_type=fm loss=logistic
+	Reloads        int64     `json:"reloads"`
+	ReloadFailures int64     `json:"reload_failures"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// ModelHolder 可热更新的预测模型
+// 新模型在调用方的goroutine中完整加载并校验后原子替换，替换前后的请求分别使用旧模型和新模型；
+// 加载失败时继续使用旧模型。加载期间新旧两个模型同时在内存中
+type ModelHolder struct {
+	path      string
+	format    string
+	factorNum int
+	current   atomic.Value // *LoadedModel
+	reloadMu  sync.Mutex   // 串行化加载
+	reloads   int64        // 成功的热更新次数，不含首次加载
+	failures  int64        // 失败的热更新次数
+	lastErr   atomic.Value // string，最近一次热更新失败的原因，成功后清空
+	afterRead func()       // 读完模型文件后调用，测试中用于模拟加载期间文件被替换
+}
+
+// loadAttempts 加载期间模型文件被替换时的最大加载次数
+const loadAttempts = 3
+
+// NewModelHolder 加载模型并创建holder，首次加载失败时返回错误
+func NewModelHolder(path, format string, factorNum int) (*ModelHolder, error) {
+	h := &ModelHolder{path: path, format: format, factorNum: factorNum}
+	lm, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	h.current.Store(lm)
+	h.lastErr.Store("")
+	return h, nil
+}
+
+// Current 当前使用的模型
+func (h *ModelHolder) Current() *LoadedModel {
+	return h.current.Load().(*LoadedModel)
+}
+
+// Reload 重新加载模型文件，成功后替换当前模型；失败时保留当前模型并返回错误
+func (h *ModelHolder) Reload() (*LoadedModel, error) {
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+	old := h.Current()
+	lm, err := h.load()
+	if err != nil {
+		atomic.AddInt64(&h.failures, 1)
+		h.lastErr.Store(err.Error())
+		fmt.Printf("model reload failed, keep version %s: %v\n", old.Version, err)
+		return nil, err
+	}
+	h.current.Store(lm)
+	atomic.AddInt64(&h.reloads, 1)
+	h.lastErr.Store("")
+	fmt.Printf("model reloaded, version: %s -> %s, features: %d\n", old.Version, lm.Version, lm.Model.FeatureNum())
+	return lm, nil
+}
+
+// Watch 每隔interval检查模型文件，文件变化且两次检查之间不再变化（写入完成）时热更新，stop关闭后返回
+// 加载失败的文件版本不再重试，直到文件再次变化
+func (h *ModelHolder) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var pending, failed fileStamp
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(h.path)
+		if err != nil {
+			continue
+		}
+		stamp := fileStamp{info.ModTime(), info.Size()}
+		cur := h.Current()
+		if stamp == (fileStamp{cur.modTime, cur.size}) || stamp == failed {
+			pending = fileStamp{}
+			continue
+		}
+		if stamp != pending {
+			pending = stamp
+			continue
+		}
+		if _, err := h.Reload(); err != nil {
+			failed = stamp
+		}
+		pending = fileStamp{}
+	}
+}
+
+// fileStamp 模型文件的修改时间和大小
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Status 热更新状态
+func (h *ModelHolder) Status() HolderStatus {
+	lm := h.Current()
+	return HolderStatus{
+		Path:           h.path,
+		Format:         h.format,
+		Version:        lm.Version,
+		LoadedAt:       lm.LoadedAt,
+		FactorNum:      lm.Model.FactorNum,
+		Features:       lm.Model.FeatureNum(),
+		Meta:           lm.Model.Meta.String(),
+		Reloads:        atomic.LoadInt64(&h.reloads),
+		ReloadFailures: atomic.LoadInt64(&h.failures),
+		LastError:      h.lastErr.Load().(string),
+	}
+}
+
+// load 加载并校验模型文件
+// 版本取自加载前的文件信息，加载后文件信息变化说明读取期间文件被替换，读到的内容与版本不一定对应，
+// 此时重新加载，使版本与Watch检查的文件信息一致
+func (h *ModelHolder) load() (*LoadedModel, error) {
+	for attempt := 1; ; attempt++ {
+		info, err := os.Stat(h.path)
+		if err != nil {
+			return nil, err
+		}
+		m, err := h.loadFile()
+		if err != nil {
+			return nil, err
+		}
+		after, err := os.Stat(h.path)
+		if err != nil {
+			return nil, err
+		}
+		if after.ModTime().Equal(info.ModTime()) && after.Size() == info.Size() {
+			return &LoadedModel{
+				Model:    m,
+				Version:  info.ModTime().UTC().Format("20060102T150405.000Z") + "-" + strconv.FormatInt(info.Size(), 10),
+				LoadedAt: time.Now(),
+				modTime:  info.ModTime(),
+				size:     info.Size(),
+			}, nil
+		}
+		if attempt == loadAttempts {
+			return nil, fmt.Errorf("model file changed during loading %d times", loadAttempts)
+		}
+	}
+}
+
+// loadFile 读取并校验模型文件
+// 二进制模型先检查头部的success_flag和factor_num，未写完的文件不会被加载；
+// 文本模型没有完成标志，发布时应先写临时文件再rename到模型路径
+func (h *ModelHolder) loadFile() (*model.PredictModel, error) {
+	if h.format == "bin" {
+		binInfo, err := model.ReadInfo(h.path)
+		if err != nil {
+			return nil, err
+		}
+		if binInfo.FactorNum != uint64(h.factorNum) {
+			return nil, fmt.Errorf("factor_num mismatch: model=%d, expected=%d", binInfo.FactorNum, h.factorNum)
+		}
+	}
+	m := model.NewPredictModel(h.factorNum)
+	if err := m.LoadModel(h.path, h.format); err != nil {
+		return nil, err
+	}
+	if h.afterRead != nil {
+		h.afterRead()
+	}
+	if err := checkServable(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// checkServable 检查模型能否用于打分服务
+func checkServable(m *model.PredictModel) error {
+	if m.Meta.ModelType != model.ModelTypeFM || m.Meta.ClassNum > 0 {
+		return fmt.Errorf("only plain fm models can be served, model: %s", m.Meta.String())
+	}
+	return nil
+}
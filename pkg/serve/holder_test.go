@@ -0,0 +1,183 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// publish 训练新模型，先写临时文件再rename到模型路径
+// 修改时间设为指定值，避免同一时钟精度内的两次写入无法区分
+func publish(t *testing.T, path, format string, factorNum, rounds int, mtime time.Time) {
+	t.Helper()
+	tmp := path + ".tmp"
+	trainModel(t, tmp, format, factorNum, rounds)
+	if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.bin")
+	base := time.Now().Add(-time.Hour)
+	publish(t, path, "bin", 2, 100, base)
+	holder, err := NewModelHolder(path, "bin", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := holder.Current()
+
+	publish(t, path, "bin", 2, 500, base.Add(time.Second))
+	lm, err := holder.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder.Current() != lm || lm.Version == first.Version || lm.Model.MuBias.Wi == first.Model.MuBias.Wi {
+		t.Fatalf("model not switched: %s -> %s", first.Version, lm.Version)
+	}
+	if st := holder.Status(); st.Reloads != 1 || st.ReloadFailures != 0 || st.Version != lm.Version || st.Features != 3 {
+		t.Fatalf("status = %+v", st)
+	}
+
+	// factor_num不一致、未写完的模型和无法解析的文件都不替换当前模型
+	publish(t, path, "bin", 3, 500, base.Add(2*time.Second))
+	if _, err := holder.Reload(); err == nil {
+		t.Fatal("expected factor_num mismatch")
+	}
+	publish(t, path, "bin", 2, 500, base.Add(3*time.Second))
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// success_flag位于版本号和前4个信息字段之后
+	if _, err := f.WriteAt(make([]byte, 8), 40); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := holder.Reload(); err == nil {
+		t.Fatal("expected incomplete model error")
+	}
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := holder.Reload(); err == nil {
+		t.Fatal("expected load error")
+	}
+	if st := holder.Status(); holder.Current() != lm || st.Reloads != 1 || st.ReloadFailures != 3 || st.LastError == "" {
+		t.Fatalf("status after failures = %+v", st)
+	}
+}
+
+func TestReloadFileReplacedDuringLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.bin")
+	base := time.Now().Add(-time.Hour)
+	publish(t, path, "bin", 2, 100, base)
+	holder, err := NewModelHolder(path, "bin", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次读完旧文件后文件被替换，重新加载后版本对应新文件
+	publish(t, path, "bin", 2, 300, base.Add(time.Second))
+	replaced := false
+	holder.afterRead = func() {
+		if !replaced {
+			replaced = true
+			publish(t, path, "bin", 2, 500, base.Add(2*time.Second))
+		}
+	}
+	lm, err := holder.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lm.modTime.Equal(info.ModTime()) || lm.size != info.Size() {
+		t.Fatalf("version %s does not match the file on disk", lm.Version)
+	}
+
+	// 每次加载期间都被替换时放弃并保留当前模型
+	n := 0
+	holder.afterRead = func() {
+		n++
+		publish(t, path, "bin", 2, 100, base.Add(time.Duration(2+n)*time.Second))
+	}
+	if _, err := holder.Reload(); err == nil {
+		t.Fatal("expected error when the file keeps changing")
+	}
+	if n != loadAttempts || holder.Current() != lm {
+		t.Fatalf("attempts = %d, current model replaced: %v", n, holder.Current() != lm)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.txt")
+	base := time.Now().Add(-time.Hour)
+	publish(t, path, "txt", 2, 100, base)
+	holder, err := NewModelHolder(path, "txt", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := holder.Current()
+	stop := make(chan struct{})
+	defer close(stop)
+	go holder.Watch(5*time.Millisecond, stop)
+
+	publish(t, path, "txt", 2, 500, base.Add(time.Second))
+	deadline := time.Now().Add(5 * time.Second)
+	for holder.Current() == first {
+		if time.Now().After(deadline) {
+			t.Fatal("model not reloaded by watch")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if st := holder.Status(); st.Reloads != 1 {
+		t.Fatalf("status = %+v", st)
+	}
+}
+
+func TestReloadAPI(t *testing.T) {
+	holder := newHolder(t)
+	server, err := NewServer(holder, NewOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.Handler()
+	do := func(method, path string) (int, HolderStatus) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var st HolderStatus
+		json.Unmarshal(rec.Body.Bytes(), &st)
+		return rec.Code, st
+	}
+
+	if code, st := do(http.MethodGet, "/admin/model"); code != http.StatusOK || st.Version != holder.Current().Version || st.Features != 3 {
+		t.Fatalf("GET /admin/model: %d %+v", code, st)
+	}
+	if code, st := do(http.MethodPost, "/admin/reload"); code != http.StatusOK || st.Reloads != 1 {
+		t.Fatalf("POST /admin/reload: %d %+v", code, st)
+	}
+	if code, _ := do(http.MethodGet, "/admin/reload"); code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /admin/reload: status %d", code)
+	}
+
+	// 加载失败返回500，仍用旧模型打分
+	if err := os.WriteFile(holder.path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := do(http.MethodPost, "/admin/reload"); code != http.StatusInternalServerError {
+		t.Fatalf("POST /admin/reload with a bad model: status %d", code)
+	}
+	if code, _ := post(t, h, `{"line":"1 a:1"}`); code != http.StatusOK {
+		t.Fatalf("predict after failed reload: status %d", code)
+	}
+}
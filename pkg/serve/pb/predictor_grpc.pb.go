@@ -0,0 +1,225 @@
+// alphaFM gRPC打分服务
+// 修改后在仓库根目录执行 make proto 重新生成Go代码
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: pkg/serve/pb/predictor.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Predictor_Predict_FullMethodName      = "/alphafm.serve.Predictor/Predict"
+	Predictor_BatchPredict_FullMethodName = "/alphafm.serve.Predictor/BatchPredict"
+	Predictor_GetModelInfo_FullMethodName = "/alphafm.serve.Predictor/GetModelInfo"
+)
+
+// PredictorClient is the client API for Predictor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PredictorClient interface {
+	// 对一个请求中的样本打分
+	Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error)
+	// 双向流打分，每个请求返回一个响应，顺序与请求一致；请求出错时以错误状态结束整个流
+	BatchPredict(ctx context.Context, opts ...grpc.CallOption) (Predictor_BatchPredictClient, error)
+	// 当前模型的版本、元信息和热更新状态
+	GetModelInfo(ctx context.Context, in *ModelInfoRequest, opts ...grpc.CallOption) (*ModelInfo, error)
+}
+
+type predictorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPredictorClient(cc grpc.ClientConnInterface) PredictorClient {
+	return &predictorClient{cc}
+}
+
+func (c *predictorClient) Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error) {
+	out := new(PredictResponse)
+	err := c.cc.Invoke(ctx, Predictor_Predict_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *predictorClient) BatchPredict(ctx context.Context, opts ...grpc.CallOption) (Predictor_BatchPredictClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Predictor_ServiceDesc.Streams[0], Predictor_BatchPredict_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &predictorBatchPredictClient{stream}
+	return x, nil
+}
+
+type Predictor_BatchPredictClient interface {
+	Send(*PredictRequest) error
+	Recv() (*PredictResponse, error)
+	grpc.ClientStream
+}
+
+type predictorBatchPredictClient struct {
+	grpc.ClientStream
+}
+
+func (x *predictorBatchPredictClient) Send(m *PredictRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *predictorBatchPredictClient) Recv() (*PredictResponse, error) {
+	m := new(PredictResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *predictorClient) GetModelInfo(ctx context.Context, in *ModelInfoRequest, opts ...grpc.CallOption) (*ModelInfo, error) {
+	out := new(ModelInfo)
+	err := c.cc.Invoke(ctx, Predictor_GetModelInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PredictorServer is the server API for Predictor service.
+// All implementations must embed UnimplementedPredictorServer
+// for forward compatibility
+type PredictorServer interface {
+	// 对一个请求中的样本打分
+	Predict(context.Context, *PredictRequest) (*PredictResponse, error)
+	// 双向流打分，每个请求返回一个响应，顺序与请求一致；请求出错时以错误状态结束整个流
+	BatchPredict(Predictor_BatchPredictServer) error
+	// 当前模型的版本、元信息和热更新状态
+	GetModelInfo(context.Context, *ModelInfoRequest) (*ModelInfo, error)
+	mustEmbedUnimplementedPredictorServer()
+}
+
+// UnimplementedPredictorServer must be embedded to have forward compatible implementations.
+type UnimplementedPredictorServer struct {
+}
+
+func (UnimplementedPredictorServer) Predict(context.Context, *PredictRequest) (*PredictResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Predict not implemented")
+}
+func (UnimplementedPredictorServer) BatchPredict(Predictor_BatchPredictServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchPredict not implemented")
+}
+func (UnimplementedPredictorServer) GetModelInfo(context.Context, *ModelInfoRequest) (*ModelInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetModelInfo not implemented")
+}
+func (UnimplementedPredictorServer) mustEmbedUnimplementedPredictorServer() {}
+
+// UnsafePredictorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PredictorServer will
+// result in compilation errors.
+type UnsafePredictorServer interface {
+	mustEmbedUnimplementedPredictorServer()
+}
+
+func RegisterPredictorServer(s grpc.ServiceRegistrar, srv PredictorServer) {
+	s.RegisterService(&Predictor_ServiceDesc, srv)
+}
+
+func _Predictor_Predict_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PredictRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).Predict(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Predictor_Predict_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictorServer).Predict(ctx, req.(*PredictRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Predictor_BatchPredict_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PredictorServer).BatchPredict(&predictorBatchPredictServer{stream})
+}
+
+type Predictor_BatchPredictServer interface {
+	Send(*PredictResponse) error
+	Recv() (*PredictRequest, error)
+	grpc.ServerStream
+}
+
+type predictorBatchPredictServer struct {
+	grpc.ServerStream
+}
+
+func (x *predictorBatchPredictServer) Send(m *PredictResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *predictorBatchPredictServer) Recv() (*PredictRequest, error) {
+	m := new(PredictRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Predictor_GetModelInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModelInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).GetModelInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Predictor_GetModelInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictorServer).GetModelInfo(ctx, req.(*ModelInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Predictor_ServiceDesc is the grpc.ServiceDesc for Predictor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Predictor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "alphafm.serve.Predictor",
+	HandlerType: (*PredictorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Predict",
+			Handler:    _Predictor_Predict_Handler,
+		},
+		{
+			MethodName: "GetModelInfo",
+			Handler:    _Predictor_GetModelInfo_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchPredict",
+			Handler:       _Predictor_BatchPredict_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pkg/serve/pb/predictor.proto",
+}
@@ -0,0 +1,289 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiongle/alphaFM-go/pkg/model"
+	"github.com/xiongle/alphaFM-go/pkg/sample"
+	"github.com/xiongle/alphaFM-go/pkg/simd"
+)
+
+// Option 打分服务选项
+type Option struct {
+	SIMDType      simd.VectorOpsType // SIMD优化类型
+	MaxConcurrent int                // 同时处理的打分请求数上限
+	QueueTimeout  time.Duration      // 请求等待处理的最长时间，超时返回429，0表示不等待
+	MaxBatch      int                // 单个请求的样本数上限
+	MaxBodyBytes  int64              // 请求体的字节数上限
+}
+
+// NewOption 创建默认服务选项
+func NewOption() *Option {
+	return &Option{
+		SIMDType:      simd.VectorOpsScalar,
+		MaxConcurrent: runtime.NumCPU(),
+		QueueTimeout:  100 * time.Millisecond,
+		MaxBatch:      1000,
+		MaxBodyBytes:  8 << 20,
+	}
+}
+
+// Sample 请求中的一个样本，features和line二选一
+type Sample struct {
+	Features map[string]float64 `json:"features,omitempty"` // 特征名到特征值
+	Line     string             `json:"line,omitempty"`     // alphaFM格式的样本行，标签列不参与打分
+}
+
+// PredictRequest 打分请求，单个样本直接写在顶层，多个样本放在samples中
+type PredictRequest struct {
+	Sample
+	Samples []Sample `json:"samples,omitempty"`
+}
+
+// PredictResponse 打分结果，按请求中样本的顺序
+type PredictResponse struct {
+	Scores       []float64 `json:"scores"`
+	ModelVersion string    `json:"model_version"` // 打分使用的模型版本
+}
+
+// scoreFeature 打分使用的特征，与PredictModel.GetScore的参数类型一致
+type scoreFeature = struct {
+	Feature string
+	Value   float64
+}
+
+// errorResponse 错误信息
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server HTTP打分服务
+// POST /predict 打分，GET /healthz 存活检查，GET /readyz 就绪检查，
+// POST /admin/reload 热更新模型，GET /admin/model 模型版本和热更新状态
+type Server struct {
+	holder  *ModelHolder
+	opt     *Option
+	simdOps simd.VectorOps // 为nil时使用标量打分
+	sem     chan struct{}  // 并发请求的信号量
+	ready   int32          // 是否就绪，停止服务前置为0使负载均衡摘除流量
+}
+
+// NewServer 创建打分服务，使用holder中的当前模型打分
+func NewServer(holder *ModelHolder, opt *Option) (*Server, error) {
+	if opt.MaxConcurrent <= 0 || opt.MaxBatch <= 0 || opt.MaxBodyBytes <= 0 || opt.QueueTimeout < 0 {
+		return nil, fmt.Errorf("invalid concurrency or request limits")
+	}
+	s := &Server{
+		holder: holder,
+		opt:    opt,
+		sem:    make(chan struct{}, opt.MaxConcurrent),
+		ready:  1,
+	}
+	if opt.SIMDType != simd.VectorOpsScalar {
+		ops, err := simd.NewVectorOps(opt.SIMDType)
+		if err != nil {
+			return nil, fmt.Errorf("SIMD initialization failed: %v", err)
+		}
+		s.simdOps = ops
+	}
+	return s, nil
+}
+
+// SetReady 设置就绪状态
+func (s *Server) SetReady(ready bool) {
+	v := int32(0)
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&s.ready, v)
+}
+
+// Ready 是否就绪
+func (s *Server) Ready() bool {
+	return atomic.LoadInt32(&s.ready) == 1
+}
+
+// Handler 返回服务的HTTP路由
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/predict", s.handlePredict)
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/admin/reload", s.handleReload)
+	mux.HandleFunc("/admin/model", s.handleModel)
+	return mux
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !s.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) handlePredict(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed, use POST")
+		return
+	}
+	if !s.acquire() {
+		writeError(w, http.StatusTooManyRequests, "too many concurrent requests")
+		return
+	}
+	defer s.release()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, s.opt.MaxBodyBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("read request error: %v", err))
+		return
+	}
+	if int64(len(body)) > s.opt.MaxBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", s.opt.MaxBodyBytes))
+		return
+	}
+	var req PredictRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	samples := req.Samples
+	single := req.Features != nil || req.Line != ""
+	switch {
+	case single && len(samples) > 0:
+		writeError(w, http.StatusBadRequest, "use either a single sample or samples, not both")
+		return
+	case single:
+		samples = []Sample{req.Sample}
+	case len(samples) == 0:
+		writeError(w, http.StatusBadRequest, "no samples")
+		return
+	case len(samples) > s.opt.MaxBatch:
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("%d samples exceed the limit %d", len(samples), s.opt.MaxBatch))
+		return
+	}
+
+	lm, scores, err := s.scoreSamples(samples)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, PredictResponse{Scores: scores, ModelVersion: lm.Version})
+}
+
+// handleReload 重新加载模型文件，加载完成后返回；失败时返回500，继续使用旧模型
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed, use POST")
+		return
+	}
+	if _, err := s.holder.Reload(); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("reload failed, keep version %s: %v", s.holder.Current().Version, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.holder.Status())
+}
+
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.holder.Status())
+}
+
+// acquire 获取处理请求的名额，最多等待QueueTimeout
+func (s *Server) acquire() bool {
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	default:
+	}
+	if s.opt.QueueTimeout == 0 {
+		return false
+	}
+	timer := time.NewTimer(s.opt.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (s *Server) release() {
+	<-s.sem
+}
+
+// scoreSamples 对一个请求中的样本打分，返回打分使用的模型
+// 同一请求内的样本使用同一个模型，不受并发热更新影响
+func (s *Server) scoreSamples(samples []Sample) (*LoadedModel, []float64, error) {
+	lm := s.holder.Current()
+	scores := make([]float64, len(samples))
+	for i, smp := range samples {
+		x, err := smp.features()
+		if err != nil {
+			return nil, nil, fmt.Errorf("sample %d: %v", i, err)
+		}
+		scores[i] = s.score(lm.Model, x)
+	}
+	return lm, scores, nil
+}
+
+// score 计算预测值（经过link函数，logistic模型按负样本采样率校准）
+func (s *Server) score(m *model.PredictModel, x []scoreFeature) float64 {
+	if s.simdOps != nil {
+		return m.Calibrate(m.GetScoreSIMD(x, m.MuBias.Wi, s.simdOps))
+	}
+	return m.Calibrate(m.GetScore(x, m.MuBias.Wi))
+}
+
+// features 转换为打分使用的特征列表，特征按名称排序使结果与map的遍历顺序无关
+func (smp *Sample) features() ([]scoreFeature, error) {
+	if smp.Features != nil && smp.Line != "" {
+		return nil, fmt.Errorf("use either features or line, not both")
+	}
+	if smp.Line != "" {
+		// 标签列不参与打分，按实数解析以接受各种训练目标的样本
+		parsed, err := sample.ParseSample(smp.Line, sample.LabelReal)
+		if err != nil {
+			return nil, err
+		}
+		x := make([]scoreFeature, len(parsed.X))
+		for i, fv := range parsed.X {
+			x[i].Feature = fv.Feature
+			x[i].Value = fv.Value
+		}
+		return x, nil
+	}
+	if smp.Features == nil {
+		return nil, fmt.Errorf("features or line required")
+	}
+	x := make([]scoreFeature, 0, len(smp.Features))
+	for name, value := range smp.Features {
+		x = append(x, scoreFeature{Feature: name, Value: value})
+	}
+	sort.Slice(x, func(i, j int) bool { return x[i].Feature < x[j].Feature })
+	return x, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
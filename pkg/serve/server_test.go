@@ -0,0 +1,162 @@
+package serve
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xiongle/alphaFM-go/pkg/model"
+)
+
+// trainModel 训练一个小模型写入path，rounds不同得到的模型不同
+func trainModel(t *testing.T, path, format string, factorNum, rounds int) {
+	t.Helper()
+	opt := model.NewTrainerOption()
+	opt.FactorNum = factorNum
+	opt.Seed = 1
+	trainer := model.NewFTRLTrainer(opt)
+	var lines []string
+	for i := 0; i < rounds; i++ {
+		lines = append(lines, "1 a:1 b:1", "0 a:1 c:1", "0 b:0.5 c:1")
+	}
+	if err := trainer.RunTask(lines); err != nil {
+		t.Fatal(err)
+	}
+	if err := trainer.OutputModel(path, format); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newHolder 训练一个txt模型并创建holder
+func newHolder(t *testing.T) *ModelHolder {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.txt")
+	trainModel(t, path, "txt", 2, 500)
+	holder, err := NewModelHolder(path, "txt", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return holder
+}
+
+func post(t *testing.T, h http.Handler, body string) (int, map[string]json.RawMessage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(body)))
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestPredict(t *testing.T) {
+	holder := newHolder(t)
+	opt := NewOption()
+	opt.MaxBatch = 3
+	server, err := NewServer(holder, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.Handler()
+	m := holder.Current().Model
+
+	want := m.GetScore([]struct {
+		Feature string
+		Value   float64
+	}{{"a", 1}, {"b", 1}}, m.MuBias.Wi)
+
+	code, resp := post(t, h, `{"features":{"b":1,"a":1}}`)
+	var scores []float64
+	if code != http.StatusOK || json.Unmarshal(resp["scores"], &scores) != nil || len(scores) != 1 {
+		t.Fatalf("single sample: %d %s", code, resp)
+	}
+	if math.Abs(scores[0]-want) > 1e-12 {
+		t.Fatalf("score = %v, want %v", scores[0], want)
+	}
+	var version string
+	if json.Unmarshal(resp["model_version"], &version) != nil || version != holder.Current().Version {
+		t.Fatalf("model_version = %s", resp["model_version"])
+	}
+
+	// 特征map和样本行可以混用，不在模型中的特征忽略
+	code, resp = post(t, h, `{"samples":[{"line":"0 a:1 b:1 unknown:1"},{"features":{"a":1,"b":1}},{"line":"1 c:1"}]}`)
+	if code != http.StatusOK || json.Unmarshal(resp["scores"], &scores) != nil || len(scores) != 3 {
+		t.Fatalf("batch: %d %s", code, resp)
+	}
+	if scores[0] != scores[1] || math.Abs(scores[0]-want) > 1e-12 || scores[2] >= 0.5 {
+		t.Fatalf("batch scores = %v", scores)
+	}
+
+	for body, status := range map[string]int{
+		`{"samples":[{"line":"x a:1"}]}`:                http.StatusBadRequest,
+		`{"samples":[{"line":"1 a:1","features":{}}]}`:  http.StatusBadRequest,
+		`{"samples":[{}]}`:                              http.StatusBadRequest,
+		`{"line":"1 a:1","samples":[{"line":"1 a:1"}]}`: http.StatusBadRequest,
+		`{"samples":[]}`:                                http.StatusBadRequest,
+		`not json`:                                      http.StatusBadRequest,
+		`{"samples":[{"line":"1 a:1"},{"line":"1 a:1"},{"line":"1 a:1"},{"line":"1 a:1"}]}`: http.StatusRequestEntityTooLarge,
+	} {
+		if code, resp := post(t, h, body); code != status || resp["error"] == nil {
+			t.Errorf("%s: status %d, want %d, response %s", body, code, status, resp)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/predict", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /predict: status %d", rec.Code)
+	}
+}
+
+func TestHealthAndReady(t *testing.T) {
+	server, err := NewServer(newHolder(t), NewOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.Handler()
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	if get("/healthz") != http.StatusOK || get("/readyz") != http.StatusOK {
+		t.Fatal("server should be healthy and ready")
+	}
+	// 停止服务时仍存活但不再就绪
+	server.SetReady(false)
+	if get("/healthz") != http.StatusOK || get("/readyz") != http.StatusServiceUnavailable {
+		t.Fatal("server should be healthy but not ready")
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	opt := NewOption()
+	opt.MaxConcurrent = 1
+	opt.QueueTimeout = 0
+	opt.MaxBodyBytes = 64
+	server, err := NewServer(newHolder(t), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.Handler()
+
+	// 占用唯一的名额
+	server.sem <- struct{}{}
+	if code, _ := post(t, h, `{"line":"1 a:1"}`); code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", code)
+	}
+	server.release()
+	if code, _ := post(t, h, `{"line":"1 a:1"}`); code != http.StatusOK {
+		t.Fatalf("status %d after release", code)
+	}
+
+	long := `{"line":"1 ` + strings.Repeat("a:1 ", 20) + `"}`
+	if code, _ := post(t, h, long); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413", code)
+	}
+}
@@ -105,5 +105,5 @@ func PrintBenchmarkResults(results []*BenchmarkResult) {
 			r.Speedup,
 		)
 	}
-	fmt.Println("================================================\n")
+	fmt.Print("================================================\n\n")
 }
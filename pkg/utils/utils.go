@@ -28,15 +28,20 @@ func Uniform() float64 {
 
 // Gaussian 生成标准正态分布随机数 (Box-Muller变换的极坐标形式)
 func Gaussian() float64 {
+	return GaussianFrom(Uniform)
+}
+
+// GaussianFrom 使用指定的[0,1)均匀分布随机源生成标准正态分布随机数
+func GaussianFrom(uniform func() float64) float64 {
 	var u, v, x, y, Q float64
 	for {
 		for {
-			u = Uniform()
+			u = uniform()
 			if u != 0.0 {
 				break
 			}
 		}
-		v = 1.7156 * (Uniform() - 0.5)
+		v = 1.7156 * (uniform() - 0.5)
 		x = u - 0.449871
 		y = math.Abs(v) + 0.386595
 		Q = x*x + y*(0.19600*y-0.25472*x)
@@ -0,0 +1,26 @@
+#!/bin/bash
+
+# 对比加锁训练与Hogwild训练：在test_data.txt上分别训练，评估两个模型的AUC和logloss
+# test_data.txt只有10行，训练集由它重复REPEAT次得到，使多个线程同时处理不同批次
+#
+# 用法（项目根目录）：make && ./scripts/compare_hogwild.sh [REPEAT] [CORE]
+
+set -e
+
+REPEAT=${1:-2000}
+CORE=${2:-4}
+TEST_DATA="test_data.txt"
+PARAMS="-dim 1,1,4 -core $CORE -seed 7 -init_stdev 0.1"
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+for i in $(seq "$REPEAT"); do
+    cat "$TEST_DATA"
+done > "$TMP_DIR/train.txt"
+echo "train: $(wc -l < "$TMP_DIR/train.txt") lines, core: $CORE"
+
+for hogwild in 0 1; do
+    ./bin/fm_train $PARAMS -hogwild $hogwild -m "$TMP_DIR/model_$hogwild.txt" < "$TMP_DIR/train.txt" > /dev/null
+    result=$(./bin/fm_predict -m "$TMP_DIR/model_$hogwild.txt" -dim 4 -eval 1 < "$TEST_DATA" | grep -E '^(auc|logloss):' | tr '\n' ' ')
+    echo "hogwild=$hogwild $result"
+done